@@ -2,65 +2,101 @@ package cacher
 
 import (
 	"context"
-	"errors"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// ClientOption configures a Client.
+type ClientOption func(c *Client)
+
 // New creates a new instance of the Cache client from an existing redis client. This will not close the
 // redis client.
-func New(r *redis.Client) *Client {
-	return &Client{
-		redis: r,
+func New(r redis.UniversalClient, opts ...ClientOption) *Client {
+	return NewWithStore(NewRedisStore(r), opts...)
+}
+
+// NewWithStore creates a new instance of the Cache client on top of the given Store.
+func NewWithStore(s Store, opts ...ClientOption) *Client {
+	c := &Client{
+		store:  s,
+		flight: newFlightGroup(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
 }
 
 // Client is a client that simplifies the access to the redis for common caching patterns.
 type Client struct {
-	redis *redis.Client
+	store             Store
+	flight            *flightGroup
+	compression       Compression
+	compressThreshold int
+	keyring           *Keyring
+	plaintext         bool
+	tags              []string
+	namespace         string
+	versioned         string
 }
 
 // Has checks if a key exists in the cache. It returns false if the key does not exist or there was an error. It will
 // also return the error if there is one.
 func (c *Client) Has(ctx context.Context, key string) (bool, error) {
-	cmd := c.redis.Exists(ctx, key)
-	err := cmd.Err()
+	key, err := c.key(ctx, key)
 
 	if err != nil {
 		return false, err
 	}
 
-	return cmd.Val() == 1, nil
+	return c.store.Exists(ctx, key)
 }
 
-// Forget removes a key from the cache. It returns an error if there was one.
+// Forget removes a key from the cache along with any shadow copy kept by WithStaleIfError. It returns an error if there
+// was one.
 func (c *Client) Forget(ctx context.Context, key string) error {
-	cmd := c.redis.Del(ctx, key)
-	return cmd.Err()
-}
-
-// ForgetWithPrefix removes all keys from the cache that match the given prefix. It returns an error if there was one.
-func (c *Client) ForgetWithPrefix(ctx context.Context, prefix string) error {
-	iter := c.redis.Scan(ctx, 0, prefix, 0).Iterator()
+	key, err := c.key(ctx, key)
 
-	for iter.Next(ctx) {
-		if err := c.Forget(ctx, iter.Val()); err != nil {
-			return err
-		}
+	if err != nil {
+		return err
 	}
 
-	if err := iter.Err(); err != nil {
-		return err
+	_, err = c.store.Del(ctx, key)
+	return err
+}
+
+// ForgetWithPrefix removes all keys from the cache that start with the given prefix, along with any shadow copies kept
+// by WithStaleIfError. Characters with a special meaning in SCAN patterns are matched literally. Keys are found with
+// SCAN and each page of them is removed in a single pipeline of UNLINK commands. It returns how many keys were
+// removed, even if there was an error part way.
+func (c *Client) ForgetWithPrefix(ctx context.Context, prefix string, opts ...ForgetOption) (int64, error) {
+	prefix, err := c.key(ctx, prefix)
+
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	return c.forgetMatching(ctx, escapeGlob(prefix)+"*", opts)
 }
 
 // Put adds a value to the cache with an expiration. It returns an error if there was one.
 func (c *Client) Put(ctx context.Context, key string, value interface{}, exp time.Duration) error {
-	cmd := c.redis.Set(ctx, key, value, exp)
-	return cmd.Err()
+	data, err := toBytes(value)
+
+	if err != nil {
+		return err
+	}
+
+	key, err = c.key(ctx, key)
+
+	if err != nil {
+		return err
+	}
+
+	return c.write(ctx, key, data, exp)
 }
 
 // PutForever adds a value to the cache without an expiration. It returns an error if there was one.
@@ -68,152 +104,156 @@ func (c *Client) PutForever(ctx context.Context, key string, value interface{})
 	return c.Put(ctx, key, value, 0)
 }
 
-// Increment increments a value in the cache. It returns an error if there was one.
-func (c *Client) Increment(ctx context.Context, key string, value int64) error {
-	cmd := c.redis.IncrBy(ctx, key, value)
-	return cmd.Err()
+// Increment increments a value in the cache and returns the new value. A missing key is treated as 0. It returns an
+// error if there was one.
+func (c *Client) Increment(ctx context.Context, key string, value int64) (int64, error) {
+	key, err := c.key(ctx, key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return c.store.IncrBy(ctx, key, value)
 }
 
-// Decrement decrements a value in the cache. It returns an error if there was one.
-func (c *Client) Decrement(ctx context.Context, key string, value int64) error {
-	cmd := c.redis.DecrBy(ctx, key, value)
-	return cmd.Err()
+// Decrement decrements a value in the cache and returns the new value. A missing key is treated as 0. It returns an
+// error if there was one.
+func (c *Client) Decrement(ctx context.Context, key string, value int64) (int64, error) {
+	key, err := c.key(ctx, key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return c.store.IncrBy(ctx, key, -value)
 }
 
-// Get retrieves a value from the cache. It returns an error if there was one. If the key does not exist it will return
-// a NotFoundError.
-func (c *Client) Get(ctx context.Context, key string) (interface{}, error) {
-	cmd := c.redis.Get(ctx, key)
-	err := cmd.Err()
+// write stores the encoded value of the key, compressing and encrypting it if the client is configured to.
+func (c *Client) write(ctx context.Context, key string, data []byte, exp time.Duration) error {
+	if c.compression != NoCompression && len(data) >= c.compressThreshold {
+		compressed, err := compress(c.compression, data)
 
-	if errors.Is(err, redis.Nil) {
-		return nil, NotFoundError
+		if err != nil {
+			return err
+		}
+
+		data = compressed
+	}
+
+	if c.keyring != nil {
+		sealed, err := c.keyring.seal(key, data)
+
+		if err != nil {
+			return err
+		}
+
+		data = sealed
 	}
 
+	return c.store.Set(ctx, key, data, exp)
+}
+
+// read returns the encoded value of the key as it was passed to write.
+func (c *Client) read(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.store.Get(ctx, key)
+
 	if err != nil {
 		return nil, err
 	}
 
-	return cmd.Val(), nil
-}
+	if c.keyring != nil {
+		opened, err := c.keyring.open(key, data)
 
-// GetString returns the key as a string. If there was an error it will return an error. If the key does not exist it
-// will return a NotFoundError. If there was an error the string value will be a zero string.
-func (c *Client) GetString(ctx context.Context, key string) (string, error) {
-	cmd := c.redis.Get(ctx, key)
-	err := cmd.Err()
+		// a plain value stored before encryption was enabled may start with the encryption header
+		if err != nil && !c.plaintext {
+			return nil, err
+		}
 
-	if errors.Is(err, redis.Nil) {
-		return "", NotFoundError
+		if err == nil {
+			data = opened
+		}
 	}
 
+	return decompress(data), nil
+}
+
+// get reads the raw value of the key without any metadata stored alongside it by the Remember functions. Keys
+// holding a tombstone stored by WithNegativeTTL or an expired value only kept by WithStaleIfError are reported as not
+// found.
+func (c *Client) get(ctx context.Context, key string) ([]byte, error) {
+	key, err := c.key(ctx, key)
+
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return cmd.Val(), nil
-}
+	data, err := c.read(ctx, key)
 
-// GetBytes returns the key as a []byte. If there was an error it will return an error. If the key does not exist it
-// will return a NotFoundError. If there was an error the value will be nil.
-func (c *Client) GetBytes(ctx context.Context, key string) ([]byte, error) {
-	cmd := c.redis.Get(ctx, key)
-	err := cmd.Err()
+	if err != nil {
+		return nil, err
+	}
 
-	if errors.Is(err, redis.Nil) {
+	e := unmarshalEntry(data)
+
+	if e.tombstone || e.shadowed() {
 		return nil, NotFoundError
 	}
 
+	return e.value, nil
+}
+
+// Get retrieves a value from the cache. It returns an error if there was one. If the key does not exist it will return
+// a NotFoundError.
+func (c *Client) Get(ctx context.Context, key string) (interface{}, error) {
+	data, err := c.get(ctx, key)
+
 	if err != nil {
 		return nil, err
 	}
 
-	return cmd.Bytes()
+	return string(data), nil
+}
+
+// GetString returns the key as a string. If there was an error it will return an error. If the key does not exist it
+// will return a NotFoundError. If there was an error the string value will be a zero string.
+func (c *Client) GetString(ctx context.Context, key string) (string, error) {
+	return Get[string](ctx, c, key)
+}
+
+// GetBytes returns the key as a []byte. If there was an error it will return an error. If the key does not exist it
+// will return a NotFoundError. If there was an error the value will be nil.
+func (c *Client) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	return Get[[]byte](ctx, c, key)
 }
 
 // GetBool returns the key as a bool. If there was an error it will return an error. If the key does not exist it
 // will return a NotFoundError. If there was an error the value will be false.
 func (c *Client) GetBool(ctx context.Context, key string) (bool, error) {
-	cmd := c.redis.Get(ctx, key)
-	err := cmd.Err()
-
-	if errors.Is(err, redis.Nil) {
-		return false, NotFoundError
-	}
-
-	if err != nil {
-		return false, err
-	}
-
-	return cmd.Bool()
+	return Get[bool](ctx, c, key)
 }
 
 // GetInt returns the key as an int. If there was an error it will return an error. If the key does not exist it
 // will return a NotFoundError. If there was an error the value will be 0.
 func (c *Client) GetInt(ctx context.Context, key string) (int, error) {
-	cmd := c.redis.Get(ctx, key)
-	err := cmd.Err()
-
-	if errors.Is(err, redis.Nil) {
-		return 0, NotFoundError
-	}
-
-	if err != nil {
-		return 0, err
-	}
-
-	return cmd.Int()
+	return Get[int](ctx, c, key)
 }
 
 // GetInt64 returns the key as an int64. If there was an error it will return an error. If the key does not exist it
 // will return a NotFoundError. If there was an error the value will be 0.
 func (c *Client) GetInt64(ctx context.Context, key string) (int64, error) {
-	cmd := c.redis.Get(ctx, key)
-	err := cmd.Err()
-
-	if errors.Is(err, redis.Nil) {
-		return 0, NotFoundError
-	}
-
-	if err != nil {
-		return 0, err
-	}
-
-	return cmd.Int64()
+	return Get[int64](ctx, c, key)
 }
 
 // GetFloat32 returns the key as an float32. If there was an error it will return an error. If the key does not exist it
 // will return a NotFoundError. If there was an error the value will be 0.
 func (c *Client) GetFloat32(ctx context.Context, key string) (float32, error) {
-	cmd := c.redis.Get(ctx, key)
-	err := cmd.Err()
-
-	if errors.Is(err, redis.Nil) {
-		return 0, NotFoundError
-	}
-
-	if err != nil {
-		return 0, err
-	}
-
-	return cmd.Float32()
+	return Get[float32](ctx, c, key)
 }
 
 // GetFloat64 returns the key as an float64. If there was an error it will return an error. If the key does not exist it
 // will return a NotFoundError. If there was an error the value will be 0.
 func (c *Client) GetFloat64(ctx context.Context, key string) (float64, error) {
-	cmd := c.redis.Get(ctx, key)
-	err := cmd.Err()
-
-	if errors.Is(err, redis.Nil) {
-		return 0, NotFoundError
-	}
-
-	if err != nil {
-		return 0, err
-	}
-
-	return cmd.Float64()
+	return Get[float64](ctx, c, key)
 }
 
 // GetStringWithDefault will return the value as a string. If there was an error or the value is zero, it will return
@@ -302,252 +342,77 @@ func (c *Client) GetFloat64WithDefault(ctx context.Context, key string, defaultV
 
 // RememberString will attempt to get the value from the cache. If it is not found it will call the fetcher function to
 // get the value. It will then put the value in the cache for later. It returns the value or an error if there was one.
-func (c *Client) RememberString(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) (string, error)) (string, error) {
-	// attempt to fetch the value from the cache
-	val, err := c.GetString(ctx, key)
-
-	// if there was no error and the value isn't blank return
-	if err == nil && val != "" {
-		return val, nil
-	}
-
-	// if there was an error and it wasn't a not found error return
-	if err != nil && !errors.Is(err, NotFoundError) {
-		return "", err
-	}
-
-	// call the fetcher to get the value we should remember
-	val, err = fetcher(ctx)
-
-	if err != nil {
-		return "", err
-	}
-
-	// put the value in the cache for later
-	if err := c.Put(ctx, key, val, exp); err != nil {
-		return "", err
-	}
-
-	return val, nil
+func (c *Client) RememberString(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) (string, error), opts ...RememberOption) (string, error) {
+	return Remember(ctx, c, key, exp, fetcher, opts...)
 }
 
 // RememberStringForever is the same as RememberString but it will not expire the value.
-func (c *Client) RememberStringForever(ctx context.Context, key string, fetcher func(ctx context.Context) (string, error)) (string, error) {
-	return c.RememberString(ctx, key, 0, fetcher)
+func (c *Client) RememberStringForever(ctx context.Context, key string, fetcher func(ctx context.Context) (string, error), opts ...RememberOption) (string, error) {
+	return c.RememberString(ctx, key, 0, fetcher, opts...)
 }
 
 // RememberBool will attempt to get the value from the cache. If it is not found it will call the fetcher function to
 // get the value. It will then put the value in the cache for later. It returns the value or an error if there was one.
-func (c *Client) RememberBool(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) (bool, error)) (bool, error) {
-	// attempt to fetch the value from the cache
-	val, err := c.GetBool(ctx, key)
-
-	// if there was no error and the value isn't blank return
-	if err == nil {
-		return val, nil
-	}
-
-	// if there was an error and it wasn't a not found error return
-	if err != nil && !errors.Is(err, NotFoundError) {
-		return false, err
-	}
-
-	// call the fetcher to get the value we should remember
-	val, err = fetcher(ctx)
-
-	if err != nil {
-		return false, err
-	}
-
-	// put the value in the cache for later
-	if err := c.Put(ctx, key, val, exp); err != nil {
-		return false, err
-	}
-
-	return val, nil
+func (c *Client) RememberBool(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) (bool, error), opts ...RememberOption) (bool, error) {
+	return Remember(ctx, c, key, exp, fetcher, opts...)
 }
 
 // RememberBoolForever is the same as RememberBool but it will not expire the value.
-func (c *Client) RememberBoolForever(ctx context.Context, key string, fetcher func(ctx context.Context) (bool, error)) (bool, error) {
-	return c.RememberBool(ctx, key, 0, fetcher)
+func (c *Client) RememberBoolForever(ctx context.Context, key string, fetcher func(ctx context.Context) (bool, error), opts ...RememberOption) (bool, error) {
+	return c.RememberBool(ctx, key, 0, fetcher, opts...)
 }
 
 // RememberBytes will attempt to get the value from the cache. If it is not found it will call the fetcher function to
 // get the value. It will then put the value in the cache for later. It returns the value or an error if there was one.
-func (c *Client) RememberBytes(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) ([]byte, error)) ([]byte, error) {
-	// attempt to fetch the value from the cache
-	val, err := c.GetBytes(ctx, key)
-
-	// if there was no error and the value isn't blank return
-	if err == nil && len(val) > 0 {
-		return val, nil
-	}
-
-	// if there was an error and it wasn't a not found error return
-	if err != nil && !errors.Is(err, NotFoundError) {
-		return nil, err
-	}
-
-	// call the fetcher to get the value we should remember
-	val, err = fetcher(ctx)
-
-	if err != nil {
-		return nil, err
-	}
-
-	// put the value in the cache for later
-	if err := c.Put(ctx, key, val, exp); err != nil {
-		return nil, err
-	}
-
-	return val, nil
+func (c *Client) RememberBytes(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) ([]byte, error), opts ...RememberOption) ([]byte, error) {
+	return Remember(ctx, c, key, exp, fetcher, opts...)
 }
 
 // RememberBytesForever is the same as RememberString but it will not expire the value.
-func (c *Client) RememberBytesForever(ctx context.Context, key string, fetcher func(ctx context.Context) ([]byte, error)) ([]byte, error) {
-	return c.RememberBytes(ctx, key, 0, fetcher)
+func (c *Client) RememberBytesForever(ctx context.Context, key string, fetcher func(ctx context.Context) ([]byte, error), opts ...RememberOption) ([]byte, error) {
+	return c.RememberBytes(ctx, key, 0, fetcher, opts...)
 }
 
 // RememberInt will attempt to get the value from the cache. If it is not found it will call the fetcher function to
 // get the value. It will then put the value in the cache for later. It returns the value or an error if there was one.
-func (c *Client) RememberInt(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) (int, error)) (int, error) {
-	// attempt to fetch the value from the cache
-	val, err := c.GetInt(ctx, key)
-
-	// if there was no error and the value isn't blank return
-	if err == nil && val != 0 {
-		return val, nil
-	}
-
-	// if there was an error and it wasn't a not found error return
-	if err != nil && !errors.Is(err, NotFoundError) {
-		return 0, err
-	}
-
-	// call the fetcher to get the value we should remember
-	val, err = fetcher(ctx)
-
-	if err != nil {
-		return 0, err
-	}
-
-	// put the value in the cache for later
-	if err := c.Put(ctx, key, val, exp); err != nil {
-		return 0, err
-	}
-
-	return val, nil
+func (c *Client) RememberInt(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) (int, error), opts ...RememberOption) (int, error) {
+	return Remember(ctx, c, key, exp, fetcher, opts...)
 }
 
 // RememberIntForever is the same as RememberInt but it will not expire the value.
-func (c *Client) RememberIntForever(ctx context.Context, key string, fetcher func(ctx context.Context) (int, error)) (int, error) {
-	return c.RememberInt(ctx, key, 0, fetcher)
+func (c *Client) RememberIntForever(ctx context.Context, key string, fetcher func(ctx context.Context) (int, error), opts ...RememberOption) (int, error) {
+	return c.RememberInt(ctx, key, 0, fetcher, opts...)
 }
 
 // RememberInt64 will attempt to get the value from the cache. If it is not found it will call the fetcher function to
 // get the value. It will then put the value in the cache for later. It returns the value or an error if there was one.
-func (c *Client) RememberInt64(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) (int64, error)) (int64, error) {
-	// attempt to fetch the value from the cache
-	val, err := c.GetInt64(ctx, key)
-
-	// if there was no error and the value isn't blank return
-	if err == nil && val != 0 {
-		return val, nil
-	}
-
-	// if there was an error and it wasn't a not found error return
-	if err != nil && !errors.Is(err, NotFoundError) {
-		return 0, err
-	}
-
-	// call the fetcher to get the value we should remember
-	val, err = fetcher(ctx)
-
-	if err != nil {
-		return 0, err
-	}
-
-	// put the value in the cache for later
-	if err := c.Put(ctx, key, val, exp); err != nil {
-		return 0, err
-	}
-
-	return val, nil
+func (c *Client) RememberInt64(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) (int64, error), opts ...RememberOption) (int64, error) {
+	return Remember(ctx, c, key, exp, fetcher, opts...)
 }
 
 // RememberInt64Forever is the same as RememberInt64 but it will not expire the value.
-func (c *Client) RememberInt64Forever(ctx context.Context, key string, fetcher func(ctx context.Context) (int64, error)) (int64, error) {
-	return c.RememberInt64(ctx, key, 0, fetcher)
+func (c *Client) RememberInt64Forever(ctx context.Context, key string, fetcher func(ctx context.Context) (int64, error), opts ...RememberOption) (int64, error) {
+	return c.RememberInt64(ctx, key, 0, fetcher, opts...)
 }
 
 // RememberFloat32 will attempt to get the value from the cache. If it is not found it will call the fetcher function to
 // get the value. It will then put the value in the cache for later. It returns the value or an error if there was one.
-func (c *Client) RememberFloat32(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) (float32, error)) (float32, error) {
-	// attempt to fetch the value from the cache
-	val, err := c.GetFloat32(ctx, key)
-
-	// if there was no error and the value isn't blank return
-	if err == nil && val != 0 {
-		return val, nil
-	}
-
-	// if there was an error and it wasn't a not found error return
-	if err != nil && !errors.Is(err, NotFoundError) {
-		return 0, err
-	}
-
-	// call the fetcher to get the value we should remember
-	val, err = fetcher(ctx)
-
-	if err != nil {
-		return 0, err
-	}
-
-	// put the value in the cache for later
-	if err := c.Put(ctx, key, val, exp); err != nil {
-		return 0, err
-	}
-
-	return val, nil
+func (c *Client) RememberFloat32(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) (float32, error), opts ...RememberOption) (float32, error) {
+	return Remember(ctx, c, key, exp, fetcher, opts...)
 }
 
 // RememberFloat32Forever is the same as RememberFloat32 but it will not expire the value.
-func (c *Client) RememberFloat32Forever(ctx context.Context, key string, fetcher func(ctx context.Context) (float32, error)) (float32, error) {
-	return c.RememberFloat32(ctx, key, 0, fetcher)
+func (c *Client) RememberFloat32Forever(ctx context.Context, key string, fetcher func(ctx context.Context) (float32, error), opts ...RememberOption) (float32, error) {
+	return c.RememberFloat32(ctx, key, 0, fetcher, opts...)
 }
 
 // RememberFloat64 will attempt to get the value from the cache. If it is not found it will call the fetcher function to
 // get the value. It will then put the value in the cache for later. It returns the value or an error if there was one.
-func (c *Client) RememberFloat64(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) (float64, error)) (float64, error) {
-	// attempt to fetch the value from the cache
-	val, err := c.GetFloat64(ctx, key)
-
-	// if there was no error and the value isn't blank return
-	if err == nil && val != 0 {
-		return val, nil
-	}
-
-	// if there was an error and it wasn't a not found error return
-	if err != nil && !errors.Is(err, NotFoundError) {
-		return 0, err
-	}
-
-	// call the fetcher to get the value we should remember
-	val, err = fetcher(ctx)
-
-	if err != nil {
-		return 0, err
-	}
-
-	// put the value in the cache for later
-	if err := c.Put(ctx, key, val, exp); err != nil {
-		return 0, err
-	}
-
-	return val, nil
+func (c *Client) RememberFloat64(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) (float64, error), opts ...RememberOption) (float64, error) {
+	return Remember(ctx, c, key, exp, fetcher, opts...)
 }
 
 // RememberFloat64Forever is the same as RememberFloat64 but it will not expire the value.
-func (c *Client) RememberFloat64Forever(ctx context.Context, key string, fetcher func(ctx context.Context) (float64, error)) (float64, error) {
-	return c.RememberFloat64(ctx, key, 0, fetcher)
+func (c *Client) RememberFloat64Forever(ctx context.Context, key string, fetcher func(ctx context.Context) (float64, error), opts ...RememberOption) (float64, error) {
+	return c.RememberFloat64(ctx, key, 0, fetcher, opts...)
 }
@@ -2,29 +2,83 @@ package cacher
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// EntityOption configures an EntityClient.
+type EntityOption func(o *entityOptions)
+
+type entityOptions struct {
+	codec      Codec
+	version    int
+	migrations map[int]func(data []byte) ([]byte, error)
+}
+
+// WithCodec sets how entities are marshalled in the cache. The default is JSONCodec.
+func WithCodec(codec Codec) EntityOption {
+	return func(o *entityOptions) {
+		o.codec = codec
+	}
+}
+
+// WithSchemaVersion stores entities along with the version of their schema. Bump the version whenever the shape of the
+// entity changes in a way that old values can't be read into. Entities stored under another version are reported as a
+// SchemaVersionError by Get and GetMany and treated as a miss by the Remember functions unless WithMigration upgrades
+// them. Values stored without a version are version 0.
+func WithSchemaVersion(version int) EntityOption {
+	return func(o *entityOptions) {
+		o.version = version
+	}
+}
+
+// WithMigration registers a function upgrading the value of an entity or list of entities, as marshalled by the Codec,
+// from schema version from to version from+1. Values stored under older versions are upgraded one version at a time
+// until they reach the version set by WithSchemaVersion.
+func WithMigration(from int, fn func(data []byte) ([]byte, error)) EntityOption {
+	return func(o *entityOptions) {
+		o.migrations[from] = fn
+	}
+}
+
 // NewEntityWithClient creates a new EntityClient with the given Client.
-func NewEntityWithClient[E any](c *Client) *EntityClient[E] {
+func NewEntityWithClient[E any](c *Client, opts ...EntityOption) *EntityClient[E] {
+	o := &entityOptions{
+		codec:      JSONCodec{},
+		migrations: make(map[int]func(data []byte) ([]byte, error)),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	return &EntityClient[E]{
-		client: c,
+		client:     c,
+		codec:      o.codec,
+		version:    o.version,
+		migrations: o.migrations,
 	}
 }
 
 // NewEntity creates a new EntityClient with the given redis client.
-func NewEntity[E any](r *redis.Client) *EntityClient[E] {
-	return NewEntityWithClient[E](New(r))
+func NewEntity[E any](r redis.UniversalClient, opts ...EntityOption) *EntityClient[E] {
+	return NewEntityWithClient[E](New(r), opts...)
+}
+
+// NewEntityWithStore creates a new EntityClient on top of the given Store.
+func NewEntityWithStore[E any](s Store, opts ...EntityOption) *EntityClient[E] {
+	return NewEntityWithClient[E](NewWithStore(s), opts...)
 }
 
 // EntityClient is a wrapper around the Client that provides a more convenient access parttern using generics. This
-// client will automatically marshal and unmarshal entities to and from the cache using JSON.
+// client will automatically marshal and unmarshal entities to and from the cache using its Codec, JSON by default.
 type EntityClient[E any] struct {
-	client *Client
+	client     *Client
+	codec      Codec
+	version    int
+	migrations map[int]func(data []byte) ([]byte, error)
 }
 
 // Has checks if the given key exists in the cache.
@@ -37,9 +91,45 @@ func (c *EntityClient[E]) Forget(ctx context.Context, key string) error {
 	return c.client.Forget(ctx, key)
 }
 
-// ForgetWithPrefix removes all keys from the cache that start with the given prefix.
-func (c *EntityClient[E]) ForgetWithPrefix(ctx context.Context, prefix string) error {
-	return c.client.ForgetWithPrefix(ctx, prefix)
+// ForgetWithPrefix removes all keys from the cache that start with the given prefix and returns how many were removed.
+// See Client.ForgetWithPrefix.
+func (c *EntityClient[E]) ForgetWithPrefix(ctx context.Context, prefix string, opts ...ForgetOption) (int64, error) {
+	return c.client.ForgetWithPrefix(ctx, prefix, opts...)
+}
+
+// Tags returns a view of the client that reads and writes keys under the given tags. See Client.Tags.
+func (c *EntityClient[E]) Tags(names ...string) *EntityClient[E] {
+	view := *c
+	view.client = c.client.Tags(names...)
+
+	return &view
+}
+
+// FlushTags removes every key written under any of the given tags.
+func (c *EntityClient[E]) FlushTags(ctx context.Context, names ...string) error {
+	return c.client.FlushTags(ctx, names...)
+}
+
+// WithNamespace returns a view of the client that prefixes every key with the namespace. See Client.WithNamespace.
+func (c *EntityClient[E]) WithNamespace(namespace string) *EntityClient[E] {
+	view := *c
+	view.client = c.client.WithNamespace(namespace)
+
+	return &view
+}
+
+// WithVersionedNamespace returns a view of the client that prefixes every key with the namespace and its current
+// generation. See Client.WithVersionedNamespace.
+func (c *EntityClient[E]) WithVersionedNamespace(namespace string) *EntityClient[E] {
+	view := *c
+	view.client = c.client.WithVersionedNamespace(namespace)
+
+	return &view
+}
+
+// FlushNamespace removes every key in the namespace of the client. See Client.FlushNamespace.
+func (c *EntityClient[E]) FlushNamespace(ctx context.Context) error {
+	return c.client.FlushNamespace(ctx)
 }
 
 // Get fetches the entity from the cache. The value will be nil if it is not found along with an error.
@@ -51,16 +141,7 @@ func (c *EntityClient[E]) Get(ctx context.Context, key string) (*E, error) {
 		return nil, err
 	}
 
-	// unmarshal the entity
-	var entity E
-
-	err = json.Unmarshal(data, &entity)
-
-	if err != nil {
-		return nil, errors.Join(EntityMarshalError, err)
-	}
-
-	return &entity, err
+	return c.unmarshal(data)
 }
 
 // GetMany fetches the entities from the cache. The value will be nil if it is not found along with an error.
@@ -72,25 +153,15 @@ func (c *EntityClient[E]) GetMany(ctx context.Context, key string) ([]*E, error)
 		return nil, err
 	}
 
-	// unmarshal the entity
-	entities := make([]*E, 0)
-
-	err = json.Unmarshal(data, &entities)
-
-	if err != nil {
-		return nil, errors.Join(EntityMarshalError, err)
-	}
-
-	return entities, err
+	return c.unmarshalMany(data)
 }
 
 // Put stores the entity in the cache for the given duration. If the duration is 0 the entity will be stored forever.
 func (c *EntityClient[E]) Put(ctx context.Context, key string, value *E, exp time.Duration) error {
-	// marshal the entity
-	data, err := json.Marshal(value)
+	data, err := c.marshal(value)
 
 	if err != nil {
-		return errors.Join(EntityMarshalError, err)
+		return err
 	}
 
 	// put the entity into the cache
@@ -104,11 +175,10 @@ func (c *EntityClient[E]) PutForever(ctx context.Context, key string, value *E)
 
 // PutMany stores the entities in the cache for the given duration. If the duration is 0 the entities will be stored forever.
 func (c *EntityClient[E]) PutMany(ctx context.Context, key string, values []*E, exp time.Duration) error {
-	// marshal the entity
-	data, err := json.Marshal(values)
+	data, err := c.marshalMany(values)
 
 	if err != nil {
-		return errors.Join(EntityMarshalError, err)
+		return err
 	}
 
 	// put the entity into the cache
@@ -122,62 +192,81 @@ func (c *EntityClient[E]) PutManyForever(ctx context.Context, key string, values
 
 // Remember fetches the entity from the cache if it exists. If it does not exist the fetcher will be called to get the
 // entity and it will be stored in the cache for the given duration. If the duration is 0 the entity will be stored forever.
-func (c *EntityClient[E]) Remember(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) (*E, error)) (*E, error) {
-	// attempt to fetch the value from the cache
-	val, err := c.Get(ctx, key)
+// Any error reading the cache, such as an entity that can no longer be unmarshalled or was stored under another schema
+// version, is treated as a miss.
+func (c *EntityClient[E]) Remember(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) (*E, error), opts ...RememberOption) (*E, error) {
+	return remember(ctx, c.client, key, exp, c.unmarshal, c.marshal, fetcher, append([]RememberOption{fetchOnError()}, opts...))
+}
 
-	// if there was no error and the value isn't blank return
-	if err == nil && val != nil {
-		return val, nil
-	}
+// RememberForever wraps Remember and stores the entity in the cache forever.
+func (c *EntityClient[E]) RememberForever(ctx context.Context, key string, fetcher func(ctx context.Context) (*E, error), opts ...RememberOption) (*E, error) {
+	return c.Remember(ctx, key, 0, fetcher, opts...)
+}
 
-	// call the fetcher to get the value we should remember
-	val, err = fetcher(ctx)
+// RememberMany fetches the entity from the cache if it exists. If it does not exist the fetcher will be called to get the
+// entity and it will be stored in the cache for the given duration. If the duration is 0 the entity will be stored forever.
+// Any error reading the cache is treated as a miss.
+func (c *EntityClient[E]) RememberMany(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) ([]*E, error), opts ...RememberOption) ([]*E, error) {
+	return remember(ctx, c.client, key, exp, c.unmarshalMany, c.marshalMany, fetcher, append([]RememberOption{fetchOnError()}, opts...))
+}
+
+// RememberManyForever wraps RememberMany and stores the entity in the cache forever.
+func (c *EntityClient[E]) RememberManyForever(ctx context.Context, key string, fetcher func(ctx context.Context) ([]*E, error), opts ...RememberOption) ([]*E, error) {
+	return c.RememberMany(ctx, key, 0, fetcher, opts...)
+}
+
+// unmarshal decodes a single entity.
+func (c *EntityClient[E]) unmarshal(data []byte) (*E, error) {
+	var entity E
+
+	data, err := c.upgrade(data)
 
 	if err != nil {
 		return nil, err
 	}
 
-	// put the value in the cache for later
-	if err := c.Put(ctx, key, val, exp); err != nil {
-		return nil, err
+	if err := c.codec.Unmarshal(data, &entity); err != nil {
+		return nil, errors.Join(EntityMarshalError, err)
 	}
 
-	return val, nil
-}
-
-// RememberForever wraps Remember and stores the entity in the cache forever.
-func (c *EntityClient[E]) RememberForever(ctx context.Context, key string, fetcher func(ctx context.Context) (*E, error)) (*E, error) {
-	return c.Remember(ctx, key, 0, fetcher)
+	return &entity, nil
 }
 
-// RememberMany fetches the entity from the cache if it exists. If it does not exist the fetcher will be called to get the
-// entity and it will be stored in the cache for the given duration. If the duration is 0 the entity will be stored forever.
-func (c *EntityClient[E]) RememberMany(ctx context.Context, key string, exp time.Duration, fetcher func(ctx context.Context) ([]*E, error)) ([]*E, error) {
-	// attempt to fetch the value from the cache
-	val, err := c.GetMany(ctx, key)
-
-	// if there was no error and the value isn't blank return
-	if err == nil && val != nil {
-		return val, nil
-	}
+// unmarshalMany decodes a list of entities.
+func (c *EntityClient[E]) unmarshalMany(data []byte) ([]*E, error) {
+	entities := make([]*E, 0)
 
-	// call the fetcher to get the value we should remember
-	val, err = fetcher(ctx)
+	data, err := c.upgrade(data)
 
 	if err != nil {
 		return nil, err
 	}
 
-	// put the value in the cache for later
-	if err := c.PutMany(ctx, key, val, exp); err != nil {
-		return nil, err
+	if err := c.codec.Unmarshal(data, &entities); err != nil {
+		return nil, errors.Join(EntityMarshalError, err)
 	}
 
-	return val, nil
+	return entities, nil
 }
 
-// RememberManyForever wraps RememberMany and stores the entity in the cache forever.
-func (c *EntityClient[E]) RememberManyForever(ctx context.Context, key string, fetcher func(ctx context.Context) ([]*E, error)) ([]*E, error) {
-	return c.RememberMany(ctx, key, 0, fetcher)
+// marshal encodes a single entity.
+func (c *EntityClient[E]) marshal(value *E) ([]byte, error) {
+	data, err := c.codec.Marshal(value)
+
+	if err != nil {
+		return nil, errors.Join(EntityMarshalError, err)
+	}
+
+	return c.stamp(data), nil
+}
+
+// marshalMany encodes a list of entities.
+func (c *EntityClient[E]) marshalMany(values []*E) ([]byte, error) {
+	data, err := c.codec.Marshal(values)
+
+	if err != nil {
+		return nil, errors.Join(EntityMarshalError, err)
+	}
+
+	return c.stamp(data), nil
 }
@@ -2,6 +2,7 @@ package cacher_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -223,7 +224,7 @@ func TestEntityClient(t *testing.T) {
 			return
 		}
 
-		err = client.ForgetWithPrefix(ctx, "prefix-*")
+		_, err = client.ForgetWithPrefix(ctx, "prefix-")
 
 		if err != nil {
 			t.Error(err)
@@ -248,4 +249,110 @@ func TestEntityClient(t *testing.T) {
 		assert.False(t, has2)
 
 	})
+	t.Run("RememberStaleWhileRevalidate", func(t *testing.T) {
+		client := cacher.NewEntityWithStore[TestEntity](cacher.NewMemoryStore())
+
+		first := &TestEntity{ID: gofakeit.UUID(), Name: gofakeit.Name()}
+		second := &TestEntity{ID: gofakeit.UUID(), Name: gofakeit.Name()}
+
+		_, _ = client.Remember(ctx, "entity-swr", time.Millisecond*50, func(ctx context.Context) (*TestEntity, error) {
+			return first, nil
+		}, cacher.WithStaleWhileRevalidate(time.Minute))
+
+		time.Sleep(time.Millisecond * 100)
+
+		value, err := client.Remember(ctx, "entity-swr", time.Millisecond*50, func(ctx context.Context) (*TestEntity, error) {
+			return second, nil
+		}, cacher.WithStaleWhileRevalidate(time.Minute))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, first, value, "should serve the stale entity straight away")
+
+		assert.Eventually(t, func() bool {
+			value, err := client.Get(ctx, "entity-swr")
+			return err == nil && value.ID == second.ID
+		}, time.Second*2, time.Millisecond*10, "should refresh the entity in the background")
+	})
+
+	t.Run("RememberNegativeTTL", func(t *testing.T) {
+		client := cacher.NewEntityWithStore[TestEntity](cacher.NewMemoryStore())
+
+		var calls int
+
+		for i := 0; i < 3; i++ {
+			_, err := client.Remember(ctx, "entity-negative", time.Minute*5, func(ctx context.Context) (*TestEntity, error) {
+				calls++
+				return nil, cacher.NotFoundError
+			}, cacher.WithNegativeTTL(time.Minute))
+
+			assert.ErrorIs(t, err, cacher.NotFoundError, "should report the entity as not found")
+		}
+
+		assert.Equal(t, 1, calls, "should remember that there is nothing to fetch")
+
+		_, err := client.Get(ctx, "entity-negative")
+		assert.ErrorIs(t, err, cacher.NotFoundError, "should read the tombstone as not found")
+	})
+
+	t.Run("RememberManyCachesEmptyLists", func(t *testing.T) {
+		client := cacher.NewEntityWithStore[TestEntity](cacher.NewMemoryStore())
+
+		var calls int
+
+		for i := 0; i < 3; i++ {
+			values, err := client.RememberMany(ctx, "entity-empty", time.Minute*5, func(ctx context.Context) ([]*TestEntity, error) {
+				calls++
+				return nil, nil
+			})
+
+			assert.NoError(t, err)
+			assert.Empty(t, values)
+		}
+
+		assert.Equal(t, 1, calls, "should only call the fetcher once")
+	})
+
+	t.Run("RememberFetchesOnError", func(t *testing.T) {
+		client := cacher.NewEntityWithStore[TestEntity](&failingStore{MemoryStore: cacher.NewMemoryStore()})
+
+		entity := &TestEntity{
+			ID:   gofakeit.UUID(),
+			Name: gofakeit.Name(),
+		}
+
+		value, err := client.Remember(ctx, "entity-failing", time.Minute*5, func(ctx context.Context) (*TestEntity, error) {
+			return entity, nil
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, entity, value, "should call the fetcher when the cache can not be read")
+
+		values, err := client.RememberMany(ctx, "entity-failing-many", time.Minute*5, func(ctx context.Context) ([]*TestEntity, error) {
+			return []*TestEntity{entity}, nil
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, []*TestEntity{entity}, values, "should call the fetcher when the cache can not be read")
+	})
+}
+
+// failingStore is a store that can not be read from.
+type failingStore struct {
+	*cacher.MemoryStore
+}
+
+func (s *failingStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, errors.New("connection refused")
 }
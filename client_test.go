@@ -3,6 +3,7 @@ package cacher_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -11,6 +12,17 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// scanCountStore counts how many times the keyspace is walked.
+type scanCountStore struct {
+	*cacher.MemoryStore
+	scans int
+}
+
+func (s *scanCountStore) Scan(ctx context.Context, match string, count int64, fn func(keys []string) error) error {
+	s.scans++
+	return s.MemoryStore.Scan(ctx, match, count, fn)
+}
+
 func TestClient(t *testing.T) {
 	t.Parallel()
 
@@ -728,19 +740,88 @@ func TestClient(t *testing.T) {
 
 	})
 
+	t.Run("ForgetWithPrefixMatchesLiterally", func(t *testing.T) {
+		_ = client.Put(ctx, "glob[1]*-a", "something", time.Minute*5)
+		_ = client.Put(ctx, "glob[1]*-b", "something", time.Minute*5)
+		_ = client.Put(ctx, "glob1x-a", "something", time.Minute*5)
+
+		var progress []int64
+
+		removed, err := client.ForgetWithPrefix(ctx, "glob[1]*", cacher.WithScanCount(1000), cacher.WithProgress(func(removed int64) {
+			progress = append(progress, removed)
+		}))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, int64(2), removed, "should only remove keys starting with the literal prefix")
+		assert.NotEmpty(t, progress, "should report progress")
+		assert.Equal(t, int64(2), progress[len(progress)-1], "should report the running total")
+
+		has, _ := client.Has(ctx, "glob1x-a")
+		assert.True(t, has, "should not treat the prefix as a pattern")
+	})
+
+	t.Run("ForgetWithPrefixRemovesShadowCopies", func(t *testing.T) {
+		_, _ = client.RememberString(ctx, "shadowed-1", time.Minute*5, func(ctx context.Context) (string, error) {
+			return "value", nil
+		}, cacher.WithStaleIfError(time.Minute))
+
+		removed, err := client.ForgetWithPrefix(ctx, "shadowed-")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, int64(1), removed, "should not count the shadow copies")
+
+		value, err := client.RememberString(ctx, "shadowed-1", time.Minute*5, func(ctx context.Context) (string, error) {
+			return "", errors.New("fetch failed")
+		}, cacher.WithStaleIfError(time.Minute))
+
+		assert.NotErrorIs(t, err, cacher.StaleValueError, "should not serve the forgotten shadow copy")
+		assert.Empty(t, value)
+	})
+
+	t.Run("ForgetWithPrefixScansOnce", func(t *testing.T) {
+		store := &scanCountStore{MemoryStore: cacher.NewMemoryStore()}
+		client := cacher.NewWithStore(store)
+
+		_, _ = client.RememberString(ctx, "scanned-1", time.Minute*5, func(ctx context.Context) (string, error) {
+			return "value", nil
+		}, cacher.WithStaleIfError(time.Minute))
+
+		_ = client.Put(ctx, "scanned-2", "value", time.Minute*5)
+
+		removed, err := client.ForgetWithPrefix(ctx, "scanned-")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, int64(2), removed, "should count every key once")
+		assert.Equal(t, 1, store.scans, "should walk the keyspace once")
+	})
+
 	t.Run("ForgetMany", func(t *testing.T) {
 		_ = client.Put(ctx, "prefix-1", "something", time.Minute*5)
 		_ = client.Put(ctx, "prefix-2", "something", time.Minute*5)
 		_ = client.Put(ctx, "prefix-3", "something", time.Minute*5)
 		_ = client.Put(ctx, "prefix-4", "something", time.Minute*5)
 
-		err := client.ForgetWithPrefix(ctx, "prefix-*")
+		removed, err := client.ForgetWithPrefix(ctx, "prefix-")
 
 		if err != nil {
 			t.Error(err)
 			return
 		}
 
+		assert.Equal(t, int64(4), removed, "should return how many keys were removed")
+
 		hasValue1, err := client.Has(ctx, "prefix-1")
 
 		if err != nil {
@@ -797,13 +878,15 @@ func TestClient(t *testing.T) {
 			return
 		}
 
-		err = client.Increment(ctx, key, 5)
+		incremented, err := client.Increment(ctx, key, 5)
 
 		if err != nil {
 			t.Error(err)
 			return
 		}
 
+		assert.Equal(t, int64(6), incremented, "should return the incremented value")
+
 		value1, err := client.GetInt(ctx, key)
 
 		if err != nil {
@@ -813,13 +896,15 @@ func TestClient(t *testing.T) {
 
 		assert.Equal(t, 6, value1, "should be equal as the value was incremented")
 
-		err = client.Decrement(ctx, key, 1)
+		decremented, err := client.Decrement(ctx, key, 1)
 
 		if err != nil {
 			t.Error(err)
 			return
 		}
 
+		assert.Equal(t, int64(5), decremented, "should return the decremented value")
+
 		value2, err := client.GetInt(ctx, key)
 
 		if err != nil {
@@ -0,0 +1,149 @@
+package cacher
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec marshals the entities of an EntityClient to and from the bytes stored in the cache. Marshal is called with a
+// pointer to an entity or a slice of pointers to entities and Unmarshal with a pointer to either.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec stores entities as JSON. It is the default Codec.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobCodec stores entities using encoding/gob. Each value carries its own type information so it is best suited to
+// large entities.
+type GobCodec struct{}
+
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// MsgpackCodec stores entities as MessagePack. Fields are named by their msgpack tags, falling back to the json tags.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+
+	return dec.Decode(v)
+}
+
+// CBORCodec stores entities as CBOR. Fields are named by their cbor tags, falling back to the json tags.
+type CBORCodec struct{}
+
+func (CBORCodec) Marshal(v interface{}) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (CBORCodec) Unmarshal(data []byte, v interface{}) error {
+	return cbor.Unmarshal(data, v)
+}
+
+// ProtoCodec stores entities whose pointers implement proto.Message using the protobuf wire format. Lists of entities
+// are stored as a sequence of size delimited messages.
+type ProtoCodec struct{}
+
+func (ProtoCodec) Marshal(v interface{}) ([]byte, error) {
+	if msg, ok := v.(proto.Message); ok {
+		return proto.Marshal(msg)
+	}
+
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("cacher: can't marshal %T as protobuf", v)
+	}
+
+	var buf bytes.Buffer
+
+	for i := 0; i < rv.Len(); i++ {
+		msg, ok := rv.Index(i).Interface().(proto.Message)
+
+		if !ok {
+			return nil, fmt.Errorf("cacher: can't marshal %T as protobuf", v)
+		}
+
+		if _, err := protodelim.MarshalTo(&buf, msg); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (ProtoCodec) Unmarshal(data []byte, v interface{}) error {
+	if msg, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, msg)
+	}
+
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Slice || rv.Elem().Type().Elem().Kind() != reflect.Pointer {
+		return fmt.Errorf("cacher: can't unmarshal protobuf into %T", v)
+	}
+
+	list := rv.Elem()
+	elem := list.Type().Elem().Elem()
+	r := bytes.NewReader(data)
+
+	for r.Len() > 0 {
+		item := reflect.New(elem)
+		msg, ok := item.Interface().(proto.Message)
+
+		if !ok {
+			return fmt.Errorf("cacher: can't unmarshal protobuf into %T", v)
+		}
+
+		if err := protodelim.UnmarshalFrom(r, msg); err != nil {
+			return err
+		}
+
+		list.Set(reflect.Append(list, item))
+	}
+
+	return nil
+}
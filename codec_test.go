@@ -0,0 +1,127 @@
+package cacher_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCodec(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	codecs := map[string]cacher.Codec{
+		"JSON":    cacher.JSONCodec{},
+		"Gob":     cacher.GobCodec{},
+		"Msgpack": cacher.MsgpackCodec{},
+		"CBOR":    cacher.CBORCodec{},
+	}
+
+	for name, codec := range codecs {
+		codec := codec
+
+		t.Run(name, func(t *testing.T) {
+			client := cacher.NewEntityWithStore[TestEntity](cacher.NewMemoryStore(), cacher.WithCodec(codec))
+
+			entity := &TestEntity{
+				ID:   gofakeit.UUID(),
+				Name: gofakeit.Name(),
+			}
+
+			err := client.Put(ctx, "codec-single", entity, time.Minute*5)
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			value, err := client.Get(ctx, "codec-single")
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			assert.Equal(t, entity, value, "should read back the entity")
+
+			err = client.PutMany(ctx, "codec-many", []*TestEntity{entity, entity}, time.Minute*5)
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			values, err := client.GetMany(ctx, "codec-many")
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			assert.Equal(t, []*TestEntity{entity, entity}, values, "should read back the entities")
+
+			var calls int
+
+			for i := 0; i < 2; i++ {
+				values, err = client.RememberMany(ctx, "codec-empty", time.Minute*5, func(ctx context.Context) ([]*TestEntity, error) {
+					calls++
+					return []*TestEntity{}, nil
+				})
+
+				assert.NoError(t, err)
+				assert.Empty(t, values)
+			}
+
+			assert.Equal(t, 1, calls, "should cache an empty list")
+		})
+	}
+
+	t.Run("Proto", func(t *testing.T) {
+		client := cacher.NewEntityWithStore[timestamppb.Timestamp](cacher.NewMemoryStore(), cacher.WithCodec(cacher.ProtoCodec{}))
+
+		first := timestamppb.New(time.Unix(1700000000, 0))
+		second := timestamppb.New(time.Unix(1800000000, 0))
+
+		err := client.Put(ctx, "codec-proto", first, time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		value, err := client.Get(ctx, "codec-proto")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, proto.Equal(first, value), "should read back the message")
+
+		err = client.PutMany(ctx, "codec-proto-many", []*timestamppb.Timestamp{first, second}, time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		values, err := client.GetMany(ctx, "codec-proto-many")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if assert.Len(t, values, 2) {
+			assert.True(t, proto.Equal(first, values[0]), "should read back the first message")
+			assert.True(t, proto.Equal(second, values[1]), "should read back the second message")
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package cacher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is an algorithm values are compressed with before they are stored, see WithCompression.
+type Compression byte
+
+const (
+	// NoCompression stores values as is.
+	NoCompression Compression = iota
+
+	// Gzip compresses values with gzip at the default level.
+	Gzip
+
+	// Zstd compresses values with zstd at the default level.
+	Zstd
+
+	// Snappy compresses values with the snappy block format, trading ratio for speed.
+	Snappy
+)
+
+// compressionHeader is the first byte of a compressed value with the algorithm in the low bits. Bytes from 0xf8 up
+// never appear in UTF-8 so text and JSON values are never mistaken for compressed ones.
+const compressionHeader = 0xf8
+
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+		return zstd.NewWriter(nil)
+	})
+
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil)
+	})
+)
+
+// WithCompression compresses values of at least threshold bytes with the given algorithm before storing them.
+// Compressed values start with a header byte identifying the algorithm so every Client reads them regardless of its
+// own compression, and values stored without one keep being read as is. A value is stored uncompressed if compressing
+// it does not make it smaller. Compressed values can not be read by other redis clients or changed by Increment.
+func WithCompression(algorithm Compression, threshold int) ClientOption {
+	return func(c *Client) {
+		c.compression = algorithm
+		c.compressThreshold = threshold
+	}
+}
+
+// compress returns the data compressed with the algorithm behind a header byte, or the data as is when it would not
+// get any smaller.
+func compress(algorithm Compression, data []byte) ([]byte, error) {
+	var compressed []byte
+
+	switch algorithm {
+	case Gzip:
+		var buf bytes.Buffer
+
+		w := gzip.NewWriter(&buf)
+
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+
+		compressed = buf.Bytes()
+	case Zstd:
+		enc, err := zstdEncoder()
+
+		if err != nil {
+			return nil, err
+		}
+
+		compressed = enc.EncodeAll(data, nil)
+	case Snappy:
+		compressed = s2.EncodeSnappy(nil, data)
+	default:
+		return data, nil
+	}
+
+	if len(compressed)+1 >= len(data) {
+		return data, nil
+	}
+
+	return append([]byte{compressionHeader | byte(algorithm)}, compressed...), nil
+}
+
+// decompress returns the data without compression. Data without a compression header, or that can not be
+// decompressed, was stored as is and is returned unchanged.
+func decompress(data []byte) []byte {
+	if len(data) == 0 || data[0]&^0x07 != compressionHeader {
+		return data
+	}
+
+	var (
+		plain []byte
+		err   error
+	)
+
+	switch Compression(data[0] &^ compressionHeader) {
+	case Gzip:
+		var r *gzip.Reader
+
+		r, err = gzip.NewReader(bytes.NewReader(data[1:]))
+
+		if err == nil {
+			plain, err = io.ReadAll(r)
+		}
+	case Zstd:
+		var dec *zstd.Decoder
+
+		dec, err = zstdDecoder()
+
+		if err == nil {
+			plain, err = dec.DecodeAll(data[1:], nil)
+		}
+	case Snappy:
+		plain, err = s2.Decode(nil, data[1:])
+	default:
+		return data
+	}
+
+	if err != nil {
+		return data
+	}
+
+	return plain
+}
@@ -0,0 +1,210 @@
+package cacher_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompression(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	large := strings.Repeat("hello-world ", 200)
+
+	algorithms := map[string]cacher.Compression{
+		"Gzip":   cacher.Gzip,
+		"Zstd":   cacher.Zstd,
+		"Snappy": cacher.Snappy,
+	}
+
+	for name, algorithm := range algorithms {
+		algorithm := algorithm
+
+		t.Run(name, func(t *testing.T) {
+			store := cacher.NewMemoryStore()
+			client := cacher.NewWithStore(store, cacher.WithCompression(algorithm, 1024))
+
+			err := client.Put(ctx, "compressed", large, time.Minute*5)
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			raw, err := store.Get(ctx, "compressed")
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			assert.Less(t, len(raw), len(large), "should store the value compressed")
+
+			value, err := client.GetString(ctx, "compressed")
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			assert.Equal(t, large, value, "should decompress the value")
+
+			// clients read compressed values whatever their own configuration
+			value, err = cacher.NewWithStore(store).GetString(ctx, "compressed")
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			assert.Equal(t, large, value, "should decompress the value without compression configured")
+		})
+	}
+
+	t.Run("BelowThreshold", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		client := cacher.NewWithStore(store, cacher.WithCompression(cacher.Zstd, 1024))
+
+		value := strings.Repeat("a", 512)
+
+		err := client.Put(ctx, "small", value, time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		raw, err := store.Get(ctx, "small")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, value, string(raw), "should store small values as is")
+	})
+
+	t.Run("IncompressibleStoredAsIs", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		client := cacher.NewWithStore(store, cacher.WithCompression(cacher.Gzip, 0))
+
+		err := client.Put(ctx, "counter", 41, time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		_, err = client.Increment(ctx, "counter", 1)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		value, err := client.GetInt(ctx, "counter")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, 42, value, "should keep values that do not shrink uncompressed")
+	})
+
+	t.Run("ReadsUncompressedValues", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+
+		err := cacher.NewWithStore(store).Put(ctx, "legacy", large, time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		value, err := cacher.NewWithStore(store, cacher.WithCompression(cacher.Zstd, 1024)).GetString(ctx, "legacy")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, large, value, "should read values stored before compression was enabled")
+	})
+
+	t.Run("HeaderLikeValuesReadAsIs", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore(), cacher.WithCompression(cacher.Zstd, 1024))
+
+		value := []byte{0xfa, 0x01, 0x02, 0x03}
+
+		err := client.Put(ctx, "binary", value, time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		data, err := client.GetBytes(ctx, "binary")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, value, data, "should return values that only look compressed unchanged")
+	})
+
+	t.Run("Entity", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		client := cacher.NewEntityWithClient[TestEntity](cacher.NewWithStore(store, cacher.WithCompression(cacher.Snappy, 64)))
+
+		entities := make([]*TestEntity, 0, 20)
+
+		for i := 0; i < 20; i++ {
+			entities = append(entities, &TestEntity{ID: gofakeit.UUID(), Name: "compressed entity"})
+		}
+
+		err := client.PutMany(ctx, "entities", entities, time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		values, err := client.GetMany(ctx, "entities")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, entities, values, "should read back the compressed entities")
+
+		value, err := client.Remember(ctx, "entity", time.Minute*5, func(ctx context.Context) (*TestEntity, error) {
+			return entities[0], nil
+		}, cacher.WithXFetch(1))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, entities[0], value)
+
+		value, err = client.Get(ctx, "entity")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, entities[0], value, "should strip the compression and the entry header")
+	})
+}
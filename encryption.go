@@ -0,0 +1,134 @@
+package cacher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// encryptionHeader is the first byte of an encrypted value. It shares the range of bytes that never appear in UTF-8
+// with the compression headers.
+const encryptionHeader = compressionHeader | 0x06
+
+// Keyring holds the AES keys values are encrypted with. Values are always encrypted with the current key and decrypted
+// with the key they were encrypted with, so keys can be rotated by creating a keyring with a new current key that
+// still holds the previous ones until every value encrypted with them has expired.
+type Keyring struct {
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewKeyring creates a Keyring encrypting with the key named current. Keys are named by ids of at most 255 bytes that
+// are stored alongside every value and must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256. It
+// returns an error if a key is invalid or there is no key named current.
+func NewKeyring(current string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("cacher: no key named %q", current)
+	}
+
+	k := &Keyring{
+		current: current,
+		aeads:   make(map[string]cipher.AEAD, len(keys)),
+	}
+
+	for id, key := range keys {
+		if len(id) > 255 {
+			return nil, fmt.Errorf("cacher: key id %q is longer than 255 bytes", id)
+		}
+
+		block, err := aes.NewCipher(key)
+
+		if err != nil {
+			return nil, fmt.Errorf("cacher: key %q: %w", id, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+
+		if err != nil {
+			return nil, fmt.Errorf("cacher: key %q: %w", id, err)
+		}
+
+		k.aeads[id] = aead
+	}
+
+	return k, nil
+}
+
+// WithEncryption encrypts values with AES-GCM using the keys of the keyring before storing them. Encrypted values are
+// bound to their key so they can not be moved to another key unnoticed. Values stored before encryption was enabled
+// are still read as is unless they start with the byte 0xfe that marks encrypted values, which binary values such as
+// gob output can, use WithPlaintextFallback to read those too. Values that can not be decrypted are reported as a
+// DecryptionError by the Get functions and treated as a miss by the Remember functions. Encrypted values can not be
+// changed by Increment.
+func WithEncryption(keyring *Keyring) ClientOption {
+	return func(c *Client) {
+		c.keyring = keyring
+	}
+}
+
+// WithPlaintextFallback reads values that can not be decrypted as the plain values they were stored as, so every value
+// stored before WithEncryption was enabled can still be read. Values encrypted with a key missing from the keyring or
+// tampered with are returned undecrypted instead of as a DecryptionError, only use it while moving a cache to
+// encryption.
+func WithPlaintextFallback() ClientOption {
+	return func(c *Client) {
+		c.plaintext = true
+	}
+}
+
+// seal encrypts the value of the key with the current key as the encryption header, the length and id of the key, the
+// nonce and the sealed value.
+func (k *Keyring) seal(key string, data []byte) ([]byte, error) {
+	aead := k.aeads[k.current]
+
+	sealed := make([]byte, 0, 2+len(k.current)+aead.NonceSize()+len(data)+aead.Overhead())
+	sealed = append(sealed, encryptionHeader, byte(len(k.current)))
+	sealed = append(sealed, k.current...)
+
+	nonce := sealed[len(sealed) : len(sealed)+aead.NonceSize()]
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed = sealed[:len(sealed)+len(nonce)]
+
+	return aead.Seal(sealed, nonce, data, []byte(key)), nil
+}
+
+// open decrypts the value of the key with the key it was encrypted with. Values without the encryption header are
+// returned as is.
+func (k *Keyring) open(key string, data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != encryptionHeader {
+		return data, nil
+	}
+
+	idLen := int(data[1])
+
+	if len(data) < 2+idLen {
+		return nil, errors.Join(DecryptionError, errors.New("value is truncated"))
+	}
+
+	id := string(data[2 : 2+idLen])
+	aead, ok := k.aeads[id]
+
+	if !ok {
+		return nil, errors.Join(DecryptionError, fmt.Errorf("no key named %q", id))
+	}
+
+	rest := data[2+idLen:]
+
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.Join(DecryptionError, errors.New("value is truncated"))
+	}
+
+	plain, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], []byte(key))
+
+	if err != nil {
+		return nil, errors.Join(DecryptionError, err)
+	}
+
+	return plain, nil
+}
@@ -0,0 +1,230 @@
+package cacher_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryption(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	key1 := bytes.Repeat([]byte{1}, 32)
+	key2 := bytes.Repeat([]byte{2}, 32)
+
+	keyring1, err := cacher.NewKeyring("key-1", map[string][]byte{"key-1": key1})
+
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	// the rotated keyring encrypts with the new key and still decrypts with the old one
+	keyring2, err := cacher.NewKeyring("key-2", map[string][]byte{"key-1": key1, "key-2": key2})
+
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		client := cacher.NewWithStore(store, cacher.WithEncryption(keyring1))
+
+		err := client.Put(ctx, "secret", "hello-world", time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		raw, err := store.Get(ctx, "secret")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.NotContains(t, string(raw), "hello-world", "should not store the plain value")
+
+		value, err := client.GetString(ctx, "secret")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", value)
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+
+		err := cacher.NewWithStore(store, cacher.WithEncryption(keyring1)).Put(ctx, "rotated", "old-value", time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		client := cacher.NewWithStore(store, cacher.WithEncryption(keyring2))
+
+		value, err := client.GetString(ctx, "rotated")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "old-value", value, "should decrypt values written under the previous key")
+
+		err = client.Put(ctx, "rotated", "new-value", time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		_, err = cacher.NewWithStore(store, cacher.WithEncryption(keyring1)).GetString(ctx, "rotated")
+		assert.ErrorIs(t, err, cacher.DecryptionError, "should not decrypt values written under an unknown key")
+	})
+
+	t.Run("BoundToKey", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		client := cacher.NewWithStore(store, cacher.WithEncryption(keyring1))
+
+		_ = client.Put(ctx, "bound-1", "value", time.Minute*5)
+
+		raw, err := store.Get(ctx, "bound-1")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		_ = store.Set(ctx, "bound-2", raw, time.Minute*5)
+
+		_, err = client.GetString(ctx, "bound-2")
+		assert.ErrorIs(t, err, cacher.DecryptionError, "should not decrypt a value copied to another key")
+	})
+
+	t.Run("ReadsUnencryptedValues", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+
+		_ = cacher.NewWithStore(store).Put(ctx, "legacy", "plain", time.Minute*5)
+
+		value, err := cacher.NewWithStore(store, cacher.WithEncryption(keyring1)).GetString(ctx, "legacy")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "plain", value, "should read values stored before encryption was enabled")
+	})
+
+	t.Run("PlaintextFallback", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+
+		// a plain binary value that happens to start with the encryption header
+		legacy := []byte{0xfe, 0x01, 0x02, 0x03}
+
+		_ = store.Set(ctx, "legacy-binary", legacy, time.Minute*5)
+
+		_, err := cacher.NewWithStore(store, cacher.WithEncryption(keyring1)).GetBytes(ctx, "legacy-binary")
+		assert.ErrorIs(t, err, cacher.DecryptionError, "should not guess that the value is plain by default")
+
+		client := cacher.NewWithStore(store, cacher.WithEncryption(keyring1), cacher.WithPlaintextFallback())
+
+		value, err := client.GetBytes(ctx, "legacy-binary")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, legacy, value, "should read the plain value")
+
+		_ = client.Put(ctx, "encrypted", "hello-world", time.Minute*5)
+
+		decrypted, _ := client.GetString(ctx, "encrypted")
+		assert.Equal(t, "hello-world", decrypted, "should still decrypt encrypted values")
+	})
+
+	t.Run("Compressed", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		client := cacher.NewWithStore(store, cacher.WithCompression(cacher.Zstd, 64), cacher.WithEncryption(keyring1))
+
+		large := strings.Repeat("hello-world ", 200)
+
+		err := client.Put(ctx, "compressed", large, time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		raw, err := store.Get(ctx, "compressed")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Less(t, len(raw), len(large), "should compress before encrypting")
+
+		value, err := client.GetString(ctx, "compressed")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, large, value)
+	})
+
+	t.Run("RememberRefetchesUndecryptable", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+
+		_ = cacher.NewWithStore(store, cacher.WithEncryption(keyring2)).Put(ctx, "remember", "unreadable", time.Minute*5)
+
+		client := cacher.NewEntityWithClient[TestEntity](cacher.NewWithStore(store, cacher.WithEncryption(keyring1)))
+
+		entity := &TestEntity{ID: "entity-id", Name: "entity-name"}
+
+		value, err := client.Remember(ctx, "remember", time.Minute*5, func(ctx context.Context) (*TestEntity, error) {
+			return entity, nil
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, entity, value, "should call the fetcher when the value can not be decrypted")
+
+		value, err = client.Get(ctx, "remember")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, entity, value)
+	})
+
+	t.Run("InvalidKeyring", func(t *testing.T) {
+		_, err := cacher.NewKeyring("missing", map[string][]byte{"key-1": key1})
+		assert.Error(t, err, "should require the current key")
+
+		_, err = cacher.NewKeyring("short", map[string][]byte{"short": []byte("too-short")})
+		assert.Error(t, err, "should reject keys of an invalid size")
+	})
+}
@@ -0,0 +1,137 @@
+package cacher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+)
+
+// entryMagic marks values stored with metadata by the Remember functions. Values stored with Put are kept as is so
+// they can still be read by other redis clients.
+const entryMagic = "\x00cacher\x01"
+
+// entryTombstone flags an entry recording that the fetcher found nothing.
+const entryTombstone = 1 << 0
+
+// entryShadow flags an entry kept past its expiration as the shadow copy of WithStaleIfError, the time it expires at
+// follows the other metadata.
+const entryShadow = 1 << 1
+
+// entry is a cached value along with the metadata the Remember functions use to decide when to recompute it.
+type entry struct {
+	value []byte
+
+	// delta is how long the fetcher took to compute the value
+	delta time.Duration
+
+	// expiresAt is when the value logically expires, it is zero if the value never expires
+	expiresAt time.Time
+
+	// tombstone is set when the entry records that the value does not exist
+	tombstone bool
+
+	// shadowAt is when the value expires and is only kept as the shadow copy of WithStaleIfError, it is zero if the
+	// entry is not kept past its expiration
+	shadowAt time.Time
+}
+
+// shadowed reports whether the entry has expired and is only kept as a shadow copy.
+func (e *entry) shadowed() bool {
+	return !e.shadowAt.IsZero() && !time.Now().Before(e.shadowAt)
+}
+
+// marshal encodes the entry as the magic header, the varint encoded flags and metadata and the value.
+func (e *entry) marshal() []byte {
+	var flags uint64
+
+	if e.tombstone {
+		flags |= entryTombstone
+	}
+
+	if !e.shadowAt.IsZero() {
+		flags |= entryShadow
+	}
+
+	data := make([]byte, 0, len(entryMagic)+4*binary.MaxVarintLen64+len(e.value))
+	data = append(data, entryMagic...)
+	data = binary.AppendUvarint(data, flags)
+	data = binary.AppendVarint(data, int64(e.delta))
+
+	if e.expiresAt.IsZero() {
+		data = binary.AppendVarint(data, 0)
+	} else {
+		data = binary.AppendVarint(data, e.expiresAt.UnixNano())
+	}
+
+	if !e.shadowAt.IsZero() {
+		data = binary.AppendVarint(data, e.shadowAt.UnixNano())
+	}
+
+	return append(data, e.value...)
+}
+
+// sameAs reports whether both entries were stored by the same call to the fetcher.
+func (e *entry) sameAs(other *entry) bool {
+	return e.tombstone == other.tombstone && e.delta == other.delta && e.expiresAt.Equal(other.expiresAt) &&
+		e.shadowAt.Equal(other.shadowAt) && bytes.Equal(e.value, other.value)
+}
+
+// unmarshalEntry decodes a value read from the Store. Values without the magic header, or that can not be decoded,
+// are returned as is without any metadata.
+func unmarshalEntry(data []byte) *entry {
+	plain := &entry{value: data}
+
+	if !bytes.HasPrefix(data, []byte(entryMagic)) {
+		return plain
+	}
+
+	rest := data[len(entryMagic):]
+
+	flags, n := binary.Uvarint(rest)
+
+	if n <= 0 {
+		return plain
+	}
+
+	rest = rest[n:]
+
+	delta, n := binary.Varint(rest)
+
+	if n <= 0 {
+		return plain
+	}
+
+	rest = rest[n:]
+
+	expiresAt, n := binary.Varint(rest)
+
+	if n <= 0 {
+		return plain
+	}
+
+	rest = rest[n:]
+
+	e := &entry{
+		delta:     time.Duration(delta),
+		tombstone: flags&entryTombstone != 0,
+	}
+
+	if expiresAt != 0 {
+		e.expiresAt = time.Unix(0, expiresAt)
+	}
+
+	if flags&entryShadow != 0 {
+		shadowAt, n := binary.Varint(rest)
+
+		if n <= 0 {
+			return plain
+		}
+
+		rest = rest[n:]
+		e.shadowAt = time.Unix(0, shadowAt)
+	}
+
+	e.value = rest
+
+	return e
+}
@@ -7,3 +7,22 @@ var NotFoundError = errors.New("key not found")
 
 // EntityMarshalError is returned when an entity cannot be marshalled or unmarshalled.
 var EntityMarshalError = errors.New("error marshalling entity")
+
+// StaleValueError is returned along with an expired value when the fetcher failed and WithStaleIfError is used.
+var StaleValueError = errors.New("serving a stale value")
+
+// DecryptionError is returned when a value encrypted with WithEncryption cannot be decrypted.
+var DecryptionError = errors.New("error decrypting value")
+
+// SchemaVersionError is returned when an entity was stored under a schema version the EntityClient can't migrate.
+var SchemaVersionError = errors.New("entity schema version mismatch")
+
+// LockNotHeldError is returned when releasing or extending a lock that is not held, because it was never acquired, was
+// already released or has expired.
+var LockNotHeldError = errors.New("lock not held")
+
+// LockTimeoutError is returned by Lock.Block when the lock could not be acquired in time.
+var LockTimeoutError = errors.New("timed out waiting for lock")
+
+// ScriptNotSupportedError is returned by Client.Run when the client is not backed by redis.
+var ScriptNotSupportedError = errors.New("store does not support scripts")
@@ -0,0 +1,207 @@
+package cacher
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// flightGroup coalesces concurrent calls for the same key into a single call whose result is shared by every caller.
+// Unlike a plain singleflight the shared call runs with a context that lives as long as the longest waiting caller and
+// is cancelled as soon as no caller is waiting for it.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	ctx     *flightContext
+	waiters int
+	done    chan struct{}
+	val     interface{}
+	err     error
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{
+		calls: make(map[string]*flight),
+	}
+}
+
+// do calls fn once for every group of concurrent callers of the key and waits for its result. A caller whose context
+// is done stops waiting and returns the context error.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+
+	f, ok := g.calls[key]
+
+	// a call that ran past its deadline would only return that error so start a fresh one
+	if ok && f.ctx.Err() == nil {
+		f.ctx.join(ctx)
+	} else {
+		f = &flight{
+			ctx:  newFlightContext(ctx),
+			done: make(chan struct{}),
+		}
+
+		g.calls[key] = f
+
+		go g.run(key, f, fn)
+	}
+
+	f.waiters++
+
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		g.leave(key, f, ctx.Err())
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) run(key string, f *flight, fn func(ctx context.Context) (interface{}, error)) {
+	f.val, f.err = fn(f.ctx)
+
+	g.mu.Lock()
+
+	if g.calls[key] == f {
+		delete(g.calls, key)
+	}
+
+	g.mu.Unlock()
+
+	close(f.done)
+
+	// release the deadline timer
+	f.ctx.cancel(context.Canceled)
+}
+
+// leave removes a caller that stopped waiting and cancels the call with the error of the last caller once nobody is
+// waiting for it. The call is forgotten straight away so later callers start a fresh one instead of joining a
+// cancelled call.
+func (g *flightGroup) leave(key string, f *flight, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	f.waiters--
+
+	if f.waiters > 0 {
+		return
+	}
+
+	if g.calls[key] == f {
+		delete(g.calls, key)
+	}
+
+	f.ctx.cancel(err)
+}
+
+// flightContext is the context a shared call runs with. It carries the values of the caller that started the call and
+// the latest deadline of every caller that joined it, a caller without a deadline removes the deadline.
+type flightContext struct {
+	parent   context.Context
+	mu       sync.Mutex
+	done     chan struct{}
+	err      error
+	deadline time.Time
+	timer    *time.Timer
+}
+
+func newFlightContext(ctx context.Context) *flightContext {
+	fc := &flightContext{
+		parent: context.WithoutCancel(ctx),
+		done:   make(chan struct{}),
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		// hold the lock so a deadline that has already passed can not fire before the timer is set
+		fc.mu.Lock()
+		fc.deadline = deadline
+		fc.timer = time.AfterFunc(time.Until(deadline), fc.expire)
+		fc.mu.Unlock()
+	}
+
+	return fc
+}
+
+// join extends the deadline to cover another caller.
+func (fc *flightContext) join(ctx context.Context) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	// already done or without a deadline so there is nothing to extend
+	if fc.err != nil || fc.deadline.IsZero() {
+		return
+	}
+
+	deadline, ok := ctx.Deadline()
+
+	if !ok {
+		fc.deadline = time.Time{}
+		fc.timer.Stop()
+
+		return
+	}
+
+	if deadline.After(fc.deadline) {
+		fc.deadline = deadline
+		fc.timer.Reset(time.Until(deadline))
+	}
+}
+
+// expire cancels the context if the deadline has passed, the timer may fire just before the deadline is extended.
+func (fc *flightContext) expire() {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if fc.deadline.IsZero() || time.Now().Before(fc.deadline) {
+		return
+	}
+
+	fc.cancelLocked(context.DeadlineExceeded)
+}
+
+func (fc *flightContext) cancel(err error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.cancelLocked(err)
+}
+
+func (fc *flightContext) cancelLocked(err error) {
+	if fc.err != nil {
+		return
+	}
+
+	fc.err = err
+	close(fc.done)
+
+	if fc.timer != nil {
+		fc.timer.Stop()
+	}
+}
+
+func (fc *flightContext) Deadline() (time.Time, bool) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	return fc.deadline, !fc.deadline.IsZero()
+}
+
+func (fc *flightContext) Done() <-chan struct{} {
+	return fc.done
+}
+
+func (fc *flightContext) Err() error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	return fc.err
+}
+
+func (fc *flightContext) Value(key any) any {
+	return fc.parent.Value(key)
+}
@@ -0,0 +1,57 @@
+package cacher
+
+import "context"
+
+// ForgetOption configures how ForgetWithPrefix walks and removes keys.
+type ForgetOption func(o *forgetOptions)
+
+type forgetOptions struct {
+	count    int64
+	progress func(removed int64)
+}
+
+// WithScanCount sets the COUNT hint passed to SCAN, which is how many keys redis examines per page. Each page of
+// matching keys is removed in a single pipeline so larger counts mean fewer round trips but longer commands. The
+// default of 0 leaves it to the store, redis examines 10 keys per page.
+func WithScanCount(count int64) ForgetOption {
+	return func(o *forgetOptions) {
+		o.count = count
+	}
+}
+
+// WithProgress sets a function called with the total number of keys removed so far after every page of keys.
+func WithProgress(fn func(removed int64)) ForgetOption {
+	return func(o *forgetOptions) {
+		o.progress = fn
+	}
+}
+
+// forgetMatching removes every key matching the pattern. The shadow copies kept by WithStaleIfError live in the keys
+// themselves so they are removed along with them. It returns how many keys were removed.
+func (c *Client) forgetMatching(ctx context.Context, match string, opts []ForgetOption) (int64, error) {
+	o := &forgetOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var removed int64
+
+	err := c.store.Scan(ctx, match, o.count, func(keys []string) error {
+		n, err := c.store.Del(ctx, keys...)
+
+		if err != nil {
+			return err
+		}
+
+		removed += n
+
+		if o.progress != nil {
+			o.progress(removed)
+		}
+
+		return nil
+	})
+
+	return removed, err
+}
@@ -0,0 +1,135 @@
+package cacher
+
+import "strings"
+
+// matchGlob reports whether str matches the glob style pattern using the same rules as the redis KEYS and SCAN
+// commands. It supports `*`, `?`, `[...]` classes with ranges and negation via `^`, and `\` escapes.
+func matchGlob(pattern, str string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// collapse consecutive stars
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+
+			if len(pattern) == 1 {
+				return true
+			}
+
+			for i := 0; i <= len(str); i++ {
+				if matchGlob(pattern[1:], str[i:]) {
+					return true
+				}
+			}
+
+			return false
+		case '?':
+			if len(str) == 0 {
+				return false
+			}
+
+			str = str[1:]
+		case '[':
+			if len(str) == 0 {
+				return false
+			}
+
+			var matched bool
+
+			matched, pattern = matchClass(pattern[1:], str[0])
+
+			if !matched {
+				return false
+			}
+
+			str = str[1:]
+
+			// matchClass leaves the pattern on the closing bracket
+			if len(pattern) == 0 {
+				return len(str) == 0
+			}
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+
+			fallthrough
+		default:
+			if len(str) == 0 || pattern[0] != str[0] {
+				return false
+			}
+
+			str = str[1:]
+		}
+
+		pattern = pattern[1:]
+	}
+
+	return len(str) == 0
+}
+
+// matchClass matches c against the character class at the start of pattern, which has already had the opening
+// bracket removed. It returns whether c matched and the pattern positioned on the closing bracket.
+func matchClass(pattern string, c byte) (bool, string) {
+	negate := false
+
+	if len(pattern) > 0 && pattern[0] == '^' {
+		negate = true
+		pattern = pattern[1:]
+	}
+
+	matched := false
+
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) >= 2:
+			pattern = pattern[1:]
+
+			if pattern[0] == c {
+				matched = true
+			}
+		case len(pattern) >= 3 && pattern[1] == '-':
+			start, end := pattern[0], pattern[2]
+
+			if start > end {
+				start, end = end, start
+			}
+
+			if c >= start && c <= end {
+				matched = true
+			}
+
+			pattern = pattern[2:]
+		default:
+			if pattern[0] == c {
+				matched = true
+			}
+		}
+
+		pattern = pattern[1:]
+	}
+
+	if negate {
+		matched = !matched
+	}
+
+	return matched, pattern
+}
+
+// escapeGlob escapes the characters of str that have a special meaning in a glob style pattern so it only matches
+// itself.
+func escapeGlob(str string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(str); i++ {
+		switch str[i] {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+
+		b.WriteByte(str[i])
+	}
+
+	return b.String()
+}
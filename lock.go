@@ -0,0 +1,406 @@
+package cacher
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// mutexPrefix is the reserved namespace the locks created by Client.Lock live in. It is kept apart from the locks
+// taken by WithLock so a named lock never blocks the recomputation of a cached key.
+const mutexPrefix = "cacher:mutex:"
+
+// fencePrefix is the reserved namespace holding the last fencing token handed out for every lock.
+const fencePrefix = "cacher:fence:"
+
+// lockRetryInterval is the longest time Acquire and Block wait between attempts to take a lock.
+const lockRetryInterval = time.Millisecond * 50
+
+// Locker is a distributed lock. It is implemented by the single node Lock and by RedlockLock.
+type Locker interface {
+	// TryAcquire attempts to take the lock once without waiting. It returns true if the lock was acquired.
+	TryAcquire(ctx context.Context) (bool, error)
+
+	// Acquire waits until the lock is acquired or the context is done.
+	Acquire(ctx context.Context) error
+
+	// Block waits up to wait for the lock to be acquired and returns a LockTimeoutError if it was not.
+	Block(ctx context.Context, wait time.Duration) error
+
+	// Release releases the lock and returns a LockNotHeldError if it was not held.
+	Release(ctx context.Context) error
+
+	// Extend resets the expiration of the lock to ttl and returns a LockNotHeldError if it was not held.
+	Extend(ctx context.Context, ttl time.Duration) error
+
+	// FencingToken returns the fencing token of the current acquisition, or 0 if the lock was not acquired.
+	FencingToken() int64
+
+	// Context returns a context that is cancelled once the current acquisition ends or is lost.
+	Context() context.Context
+}
+
+// LockOption configures a lock.
+type LockOption func(o *lockOptions)
+
+type lockOptions struct {
+	renew float64
+}
+
+// WithAutoRenew keeps extending the lock while it is held. A watchdog extends it to its ttl every fraction of the ttl,
+// so 0.5 extends it halfway to its expiration, fractions outside of (0, 1) use a third. Failed extensions are retried
+// until the lock expires. If it expires, or turns out to be held by someone else, the context returned by Context is
+// cancelled so work guarded by the lock can stop before another holder takes it over. The watchdog stops when the lock
+// is released.
+func WithAutoRenew(fraction float64) LockOption {
+	return func(o *lockOptions) {
+		if fraction <= 0 || fraction >= 1 {
+			fraction = 1.0 / 3
+		}
+
+		o.renew = fraction
+	}
+}
+
+func newLockOptions(opts []LockOption) *lockOptions {
+	o := &lockOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Lock returns a lock named name that expires ttl after it is acquired or extended so it is released even if its holder
+// dies. Locks are shared by every client using the same name and namespace, tags do not apply to them. A Lock may
+// be acquired again once released.
+func (c *Client) Lock(name string, ttl time.Duration, opts ...LockOption) *Lock {
+	// the lock and its fencing counter share a hash tag so they live in the same cluster slot
+	tag := "{" + c.namespace + name + "}"
+
+	return &Lock{
+		store:    c.store,
+		key:      mutexPrefix + tag,
+		fenceKey: fencePrefix + tag,
+		ttl:      ttl,
+		renew:    newLockOptions(opts).renew,
+	}
+}
+
+// Lock is a distributed lock held in the cache, see Client.Lock. Every acquisition is identified by a random owner
+// token so a holder can only release or extend the lock while it still owns it and never one taken over by another
+// holder after it expired.
+//
+// Every acquisition is also handed a fencing token, a number that is larger than the one of every earlier acquisition
+// of the lock. A holder that stalls past the expiration of its lock may still act on a resource, so pass the fencing
+// token along with every write and have the resource reject tokens lower than the highest it has seen. The counter is
+// stored in the cache without an expiration, it must not be evicted for tokens to keep increasing.
+type Lock struct {
+	holding
+
+	store    Store
+	key      string
+	fenceKey string
+	ttl      time.Duration
+	renew    float64
+}
+
+// TryAcquire attempts to take the lock once without waiting. It returns true if the lock was acquired.
+func (l *Lock) TryAcquire(ctx context.Context) (bool, error) {
+	token, err := newToken()
+
+	if err != nil {
+		return false, err
+	}
+
+	start := time.Now()
+
+	acquired, fence, err := l.store.SetNXAndIncr(ctx, l.key, []byte(token), l.ttl, l.fenceKey)
+
+	if err != nil || !acquired {
+		return false, err
+	}
+
+	l.hold(ctx, token, fence, start.Add(l.ttl), l.ttl, l.renew, l.Extend)
+
+	return true, nil
+}
+
+// Acquire waits until the lock is acquired or the context is done, in which case it returns the context error.
+func (l *Lock) Acquire(ctx context.Context) error {
+	return acquire(ctx, l.TryAcquire)
+}
+
+// Block waits up to wait for the lock to be acquired. It returns a LockTimeoutError if it was not acquired in time.
+func (l *Lock) Block(ctx context.Context, wait time.Duration) error {
+	return block(ctx, wait, l.TryAcquire)
+}
+
+// Release releases the lock if it is still held by this Lock. It returns a LockNotHeldError if the lock was never
+// acquired, already released or expired.
+func (l *Lock) Release(ctx context.Context) error {
+	token := l.drop()
+
+	if token == "" {
+		return LockNotHeldError
+	}
+
+	released, err := l.store.CompareAndDelete(ctx, l.key, []byte(token))
+
+	if err != nil {
+		return err
+	}
+
+	if !released {
+		return LockNotHeldError
+	}
+
+	return nil
+}
+
+// Extend resets the expiration of the lock to ttl if it is still held by this Lock. It returns a LockNotHeldError if
+// the lock was never acquired, already released or expired.
+func (l *Lock) Extend(ctx context.Context, ttl time.Duration) error {
+	token := l.current()
+
+	if token == "" {
+		return LockNotHeldError
+	}
+
+	start := time.Now()
+
+	extended, err := l.store.CompareAndExpire(ctx, l.key, []byte(token), ttl)
+
+	if err != nil {
+		return err
+	}
+
+	if !extended {
+		return LockNotHeldError
+	}
+
+	l.extended(token, start.Add(ttl))
+
+	return nil
+}
+
+// holding is the state of the current acquisition of a lock shared by every Locker.
+type holding struct {
+	mu    sync.Mutex
+	token string
+	fence int64
+	lease *lease
+}
+
+// hold records a successful acquisition that is valid until deadline and starts its lease.
+func (h *holding) hold(ctx context.Context, token string, fence int64, deadline time.Time, ttl time.Duration, renew float64, extend func(ctx context.Context, ttl time.Duration) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.token = token
+	h.fence = fence
+	h.lease = newLease(ctx, deadline, ttl, renew, extend)
+}
+
+// extended moves the lease of the acquisition identified by token forward to deadline.
+func (h *holding) extended(token string, deadline time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.token == token && h.lease != nil {
+		h.lease.extend(deadline)
+	}
+}
+
+// current returns the owner token of the current acquisition or an empty string if the lock is not held.
+func (h *holding) current() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.token
+}
+
+// drop ends the current acquisition and returns its owner token so the lock can be released.
+func (h *holding) drop() string {
+	h.mu.Lock()
+	lease := h.lease
+	h.lease = nil
+	h.mu.Unlock()
+
+	// stop renewing before giving up the token so the watchdog never sees the lock as lost
+	if lease != nil {
+		lease.end()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	token := h.token
+	h.token = ""
+	h.fence = 0
+
+	return token
+}
+
+// FencingToken returns the fencing token of the current acquisition of the lock, or 0 if it was not acquired or was
+// released.
+func (h *holding) FencingToken() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.fence
+}
+
+// Context returns a context for the work guarded by the current acquisition of the lock. It carries the values of the
+// context the lock was acquired with and is cancelled when the lock is released, or with a LockNotHeldError once its
+// ttl runs out without being extended. With WithAutoRenew it is also cancelled as soon as the lock is found to be held
+// by someone else, context.Cause returns the error. It is already cancelled if the lock is not held.
+func (h *holding) Context() context.Context {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.lease == nil {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(LockNotHeldError)
+
+		return ctx
+	}
+
+	return h.lease.ctx
+}
+
+// lease is the context of an acquisition of a lock along with the watchdog renewing it. It expires along with the
+// lock unless the lock is extended.
+type lease struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	deadline time.Time
+	expire   *time.Timer
+}
+
+// newLease starts the lease of an acquisition valid until deadline. It outlives the context the lock was acquired
+// with. With a renew fraction a watchdog extends the lock to ttl every fraction of its ttl.
+func newLease(ctx context.Context, deadline time.Time, ttl time.Duration, renew float64, extend func(ctx context.Context, ttl time.Duration) error) *lease {
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+
+	l := &lease{
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		deadline: deadline,
+	}
+
+	l.expire = time.AfterFunc(time.Until(deadline), func() {
+		l.cancel(LockNotHeldError)
+	})
+
+	if renew <= 0 {
+		close(l.done)
+		return l
+	}
+
+	go l.renew(time.Duration(float64(ttl)*renew), ttl, extend)
+
+	return l
+}
+
+// extend moves the expiration of the lease to deadline.
+func (l *lease) extend(deadline time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.deadline = deadline
+	l.expire.Reset(time.Until(deadline))
+}
+
+// expiresAt returns when the lease expires unless the lock is extended.
+func (l *lease) expiresAt() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.deadline
+}
+
+// renew extends the lock every interval until the lease ends. A failed extension is retried until the lease expires
+// since the lock is still held until then, unless the lock turns out to be held by someone else.
+func (l *lease) renew(interval, ttl time.Duration, extend func(ctx context.Context, ttl time.Duration) error) {
+	defer close(l.done)
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		// the extension must land before the lock expires
+		ctx, cancel := context.WithDeadline(l.ctx, l.expiresAt())
+		err := extend(ctx, ttl)
+		cancel()
+
+		switch {
+		case err == nil:
+			timer.Reset(interval)
+		case errors.Is(err, LockNotHeldError):
+			l.cancel(err)
+			return
+		default:
+			timer.Reset(min(interval, lockRetryInterval))
+		}
+	}
+}
+
+// end stops the watchdog and cancels the lease.
+func (l *lease) end() {
+	l.expire.Stop()
+	l.cancel(context.Canceled)
+	<-l.done
+}
+
+// acquire calls try until it acquires a lock or the context is done, in which case it returns the context error.
+func acquire(ctx context.Context, try func(ctx context.Context) (bool, error)) error {
+	for {
+		acquired, err := try(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		if acquired {
+			return nil
+		}
+
+		// spread out retries so waiting processes do not all try at once
+		timer := time.NewTimer(lockRetryInterval/2 + time.Duration(rand.Int63n(int64(lockRetryInterval/2))))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// block calls acquire for up to wait and returns a LockTimeoutError if the lock was not acquired in time.
+func block(ctx context.Context, wait time.Duration, try func(ctx context.Context) (bool, error)) error {
+	waitCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	err := acquire(waitCtx, try)
+
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return LockTimeoutError
+	}
+
+	return err
+}
@@ -0,0 +1,309 @@
+package cacher_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	mockredis "github.com/arhea/go-mock-redis"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyStore fails to extend locks while it is broken.
+type flakyStore struct {
+	*cacher.MemoryStore
+	broken atomic.Bool
+}
+
+func (s *flakyStore) CompareAndExpire(ctx context.Context, key string, value []byte, exp time.Duration) (bool, error) {
+	if s.broken.Load() {
+		return false, errors.New("connection reset")
+	}
+
+	return s.MemoryStore.CompareAndExpire(ctx, key, value, exp)
+}
+
+func TestLock(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	mock, err := mockredis.NewClient(ctx, t)
+
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	client1 := cacher.New(mock.Client())
+	client2 := cacher.New(mock.Client())
+
+	t.Run("TryAcquire", func(t *testing.T) {
+		lock1 := client1.Lock("try-acquire", time.Minute)
+		lock2 := client2.Lock("try-acquire", time.Minute)
+
+		acquired, err := lock1.TryAcquire(ctx)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, acquired, "should acquire the free lock")
+
+		acquired, err = lock2.TryAcquire(ctx)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.False(t, acquired, "should not acquire a held lock")
+
+		assert.ErrorIs(t, lock2.Release(ctx), cacher.LockNotHeldError, "should not release a lock it does not hold")
+
+		err = lock1.Release(ctx)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		acquired, _ = lock2.TryAcquire(ctx)
+		assert.True(t, acquired, "should acquire the released lock")
+
+		assert.ErrorIs(t, lock1.Release(ctx), cacher.LockNotHeldError, "should not release twice")
+		assert.NoError(t, lock2.Release(ctx))
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		lock1 := client1.Lock("block", time.Minute)
+		lock2 := client2.Lock("block", time.Minute)
+
+		_, _ = lock1.TryAcquire(ctx)
+
+		err := lock2.Block(ctx, time.Millisecond*200)
+		assert.ErrorIs(t, err, cacher.LockTimeoutError, "should time out while the lock is held")
+
+		go func() {
+			time.Sleep(time.Millisecond * 100)
+			_ = lock1.Release(ctx)
+		}()
+
+		err = lock2.Block(ctx, time.Second*2)
+		assert.NoError(t, err, "should acquire the lock once it is released")
+
+		_ = lock2.Release(ctx)
+	})
+
+	t.Run("AcquireCancelled", func(t *testing.T) {
+		lock1 := client1.Lock("acquire-cancelled", time.Minute)
+		lock2 := client2.Lock("acquire-cancelled", time.Minute)
+
+		_ = lock1.Acquire(ctx)
+
+		cancelCtx, cancel := context.WithTimeout(ctx, time.Millisecond*100)
+		defer cancel()
+
+		err := lock2.Acquire(cancelCtx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "should stop waiting with the context")
+
+		_ = lock1.Release(ctx)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		lock1 := client1.Lock("expired", time.Millisecond*200)
+		lock2 := client2.Lock("expired", time.Minute)
+
+		_, _ = lock1.TryAcquire(ctx)
+
+		err := lock2.Block(ctx, time.Second*2)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.ErrorIs(t, lock1.Extend(ctx, time.Minute), cacher.LockNotHeldError, "should not extend a lock taken over")
+		assert.ErrorIs(t, lock1.Release(ctx), cacher.LockNotHeldError, "should not release a lock taken over")
+
+		acquired, _ := client1.Lock("expired", time.Minute).TryAcquire(ctx)
+		assert.False(t, acquired, "should still be held by the new owner")
+
+		_ = lock2.Release(ctx)
+	})
+
+	t.Run("Extend", func(t *testing.T) {
+		lock := client1.Lock("extend", time.Millisecond*300)
+
+		assert.ErrorIs(t, lock.Extend(ctx, time.Minute), cacher.LockNotHeldError, "should not extend before acquiring")
+
+		_ = lock.Acquire(ctx)
+
+		err := lock.Extend(ctx, time.Minute)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		time.Sleep(time.Millisecond * 500)
+
+		acquired, _ := client2.Lock("extend", time.Minute).TryAcquire(ctx)
+		assert.False(t, acquired, "should be held past the original ttl")
+
+		assert.NoError(t, lock.Release(ctx))
+	})
+
+	t.Run("FencingToken", func(t *testing.T) {
+		lock1 := client1.Lock("fencing", time.Millisecond*200)
+		lock2 := client2.Lock("fencing", time.Minute)
+
+		assert.Zero(t, lock1.FencingToken(), "should not have a token before acquiring")
+
+		_ = lock1.Acquire(ctx)
+
+		first := lock1.FencingToken()
+		assert.Positive(t, first)
+
+		// the first holder stalls past its ttl and the lock is taken over
+		err := lock2.Block(ctx, time.Second*2)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		second := lock2.FencingToken()
+		assert.Greater(t, second, first, "should hand later acquisitions larger tokens")
+		assert.Equal(t, first, lock1.FencingToken(), "the stalled holder keeps its stale token")
+
+		_ = lock2.Release(ctx)
+		assert.Zero(t, lock2.FencingToken(), "should drop the token on release")
+
+		_ = lock2.Acquire(ctx)
+		assert.Greater(t, lock2.FencingToken(), second, "should keep increasing across acquisitions")
+
+		_ = lock2.Release(ctx)
+	})
+
+	t.Run("AutoRenew", func(t *testing.T) {
+		lock := client1.Lock("auto-renew", time.Millisecond*300, cacher.WithAutoRenew(0.3))
+
+		assert.Error(t, lock.Context().Err(), "should be cancelled before acquiring")
+
+		acquireCtx, cancel := context.WithCancel(ctx)
+
+		_ = lock.Acquire(acquireCtx)
+
+		// the lease outlives the context the lock was acquired with
+		cancel()
+
+		time.Sleep(time.Millisecond * 800)
+
+		acquired, _ := client2.Lock("auto-renew", time.Minute).TryAcquire(ctx)
+		assert.False(t, acquired, "should be held past its ttl")
+
+		lockCtx := lock.Context()
+		assert.NoError(t, lockCtx.Err(), "should not cancel while renewing")
+
+		assert.NoError(t, lock.Release(ctx))
+
+		<-lockCtx.Done()
+		assert.ErrorIs(t, context.Cause(lockCtx), context.Canceled, "should cancel on release")
+
+		time.Sleep(time.Millisecond * 400)
+
+		acquired, _ = client2.Lock("auto-renew", time.Minute).TryAcquire(ctx)
+		assert.True(t, acquired, "should stop renewing once released")
+	})
+
+	t.Run("AutoRenewLost", func(t *testing.T) {
+		lock := client1.Lock("auto-renew-lost", time.Millisecond*300, cacher.WithAutoRenew(0.3))
+
+		_ = lock.Acquire(ctx)
+
+		lockCtx := lock.Context()
+
+		// the lock is lost behind the holder's back
+		_ = mock.Client().Del(ctx, "cacher:mutex:{auto-renew-lost}").Err()
+
+		select {
+		case <-lockCtx.Done():
+		case <-time.After(time.Second * 2):
+			t.Error("should cancel the context when the lock can't be extended")
+			return
+		}
+
+		assert.ErrorIs(t, context.Cause(lockCtx), cacher.LockNotHeldError)
+		assert.ErrorIs(t, lock.Release(ctx), cacher.LockNotHeldError)
+	})
+
+	t.Run("ContextExpires", func(t *testing.T) {
+		lock := client1.Lock("context-expires", time.Millisecond*300)
+
+		_ = lock.Acquire(ctx)
+
+		lockCtx := lock.Context()
+
+		// extending the lock moves the expiration of its context forward
+		assert.NoError(t, lock.Extend(ctx, time.Millisecond*600))
+
+		time.Sleep(time.Millisecond * 400)
+		assert.NoError(t, lockCtx.Err(), "should be valid until the extended ttl")
+
+		select {
+		case <-lockCtx.Done():
+		case <-time.After(time.Second * 2):
+			t.Error("should cancel the context once the lock expires")
+			return
+		}
+
+		assert.ErrorIs(t, context.Cause(lockCtx), cacher.LockNotHeldError)
+	})
+
+	t.Run("AutoRenewRetries", func(t *testing.T) {
+		store := &flakyStore{MemoryStore: cacher.NewMemoryStore()}
+		lock := cacher.NewWithStore(store).Lock("auto-renew-retries", time.Millisecond*600, cacher.WithAutoRenew(0.25))
+
+		_ = lock.Acquire(ctx)
+
+		lockCtx := lock.Context()
+
+		// a few renewals fail while the lock is still valid
+		store.broken.Store(true)
+		time.Sleep(time.Millisecond * 350)
+		store.broken.Store(false)
+
+		time.Sleep(time.Millisecond * 600)
+		assert.NoError(t, lockCtx.Err(), "should retry failed renewals while the lock is valid")
+
+		store.broken.Store(true)
+
+		select {
+		case <-lockCtx.Done():
+		case <-time.After(time.Second * 2):
+			t.Error("should cancel the context once the lock expires")
+			return
+		}
+
+		assert.ErrorIs(t, context.Cause(lockCtx), cacher.LockNotHeldError)
+	})
+
+	t.Run("Namespaced", func(t *testing.T) {
+		lock1 := client1.WithNamespace("a").Lock("namespaced", time.Minute)
+		lock2 := client1.WithNamespace("b").Lock("namespaced", time.Minute)
+
+		acquired1, _ := lock1.TryAcquire(ctx)
+		acquired2, _ := lock2.TryAcquire(ctx)
+
+		assert.True(t, acquired1)
+		assert.True(t, acquired2, "should not share locks across namespaces")
+
+		_ = lock1.Release(ctx)
+		_ = lock2.Release(ctx)
+	})
+}
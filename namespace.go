@@ -0,0 +1,118 @@
+package cacher
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// generationPrefix is the reserved namespace holding the current generation of every versioned namespace.
+const generationPrefix = "cacher:generation:"
+
+// WithNamespace returns a view of the client that prefixes every key with the namespace followed by a colon. Calling
+// it on a namespaced client nests the namespaces. ForgetWithPrefix only removes keys within the namespace and tags
+// used through the view are scoped to it.
+func (c *Client) WithNamespace(namespace string) *Client {
+	view := *c
+	view.namespace = c.namespace + namespace + ":"
+
+	return &view
+}
+
+// WithVersionedNamespace returns a view of the client like WithNamespace whose keys also embed the current generation
+// of the namespace, so "billing" stores keys as "billing:<generation>:<key>". The generation is read from the cache on
+// every call and FlushNamespace advances it with a single INCR instead of walking the keyspace, keys of older
+// generations are never read again and expire with their own expiration. Keys stored forever are never removed, give
+// keys in a versioned namespace an expiration. Namespaces nested within a versioned namespace share its generation.
+func (c *Client) WithVersionedNamespace(namespace string) *Client {
+	view := c.WithNamespace(namespace)
+	view.versioned = view.namespace
+
+	return view
+}
+
+// FlushNamespace removes every key in the namespace of the client, including tagged keys and the shadow copies kept
+// by WithStaleIfError. A namespace created by WithVersionedNamespace is flushed by advancing its generation, any other
+// namespace is flushed by walking the keyspace which can take a while on large caches. It returns an error if the
+// client has no namespace.
+func (c *Client) FlushNamespace(ctx context.Context) error {
+	if c.namespace == "" {
+		return errors.New("cacher: client has no namespace to flush")
+	}
+
+	if c.versioned == c.namespace {
+		// seed a missing generation first, incrementing a missing counter would restart it from 1 and reuse old keys
+		if _, err := c.generation(ctx); err != nil {
+			return err
+		}
+
+		_, err := c.store.IncrBy(ctx, generationPrefix+c.versioned, 1)
+
+		return err
+	}
+
+	prefix, err := c.prefix(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = c.forgetMatching(ctx, escapeGlob(prefix)+"*", nil)
+
+	return err
+}
+
+// prefix returns the prefix of every key stored by this client, with the current generation of its versioned
+// namespace if it has one.
+func (c *Client) prefix(ctx context.Context) (string, error) {
+	if c.versioned == "" {
+		return c.namespace, nil
+	}
+
+	generation, err := c.generation(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	return c.versioned + generation + ":" + strings.TrimPrefix(c.namespace, c.versioned), nil
+}
+
+// generation returns the current generation of the versioned namespace. A namespace without a generation, because it
+// is new or its generation was evicted, starts from the current time so keys of earlier generations are never reused.
+func (c *Client) generation(ctx context.Context) (string, error) {
+	generationKey := generationPrefix + c.versioned
+
+	data, err := c.store.Get(ctx, generationKey)
+
+	if err == nil {
+		return string(data), nil
+	}
+
+	if !errors.Is(err, NotFoundError) {
+		return "", err
+	}
+
+	generation := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	created, err := c.store.SetNX(ctx, generationKey, []byte(generation), 0)
+
+	if err != nil {
+		return "", err
+	}
+
+	if created {
+		return generation, nil
+	}
+
+	// another process started the generation at the same time, use its generation
+	data, err = c.store.Get(ctx, generationKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
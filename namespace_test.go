@@ -0,0 +1,247 @@
+package cacher_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	mockredis "github.com/arhea/go-mock-redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespace(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	mock, err := mockredis.NewClient(ctx, t)
+
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	r := mock.Client()
+	client := cacher.New(r)
+
+	t.Run("PrefixesKeys", func(t *testing.T) {
+		billing := client.WithNamespace("billing")
+
+		err := billing.Put(ctx, "invoice", "hello-world", time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		value, err := r.Get(ctx, "billing:invoice").Result()
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", value, "should store the key within the namespace")
+
+		value, err = billing.GetString(ctx, "invoice")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", value)
+
+		has, _ := billing.Has(ctx, "invoice")
+		assert.True(t, has)
+
+		has, _ = client.Has(ctx, "invoice")
+		assert.False(t, has, "should not see the key outside the namespace")
+
+		value, err = billing.RememberString(ctx, "invoice", time.Minute*5, func(ctx context.Context) (string, error) {
+			return "", errors.New("should not be called")
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", value, "should remember within the namespace")
+
+		err = billing.Forget(ctx, "invoice")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		has, _ = billing.Has(ctx, "invoice")
+		assert.False(t, has, "should forget within the namespace")
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		_ = client.WithNamespace("nested").WithNamespace("inner").Put(ctx, "key", "value", time.Minute*5)
+
+		value, err := client.WithNamespace("nested:inner").GetString(ctx, "key")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "value", value)
+	})
+
+	t.Run("ScopesForgetWithPrefix", func(t *testing.T) {
+		_ = client.WithNamespace("scope-a").Put(ctx, "user-1", "value", time.Minute*5)
+		_ = client.WithNamespace("scope-b").Put(ctx, "user-1", "value", time.Minute*5)
+
+		_, err := client.WithNamespace("scope-a").ForgetWithPrefix(ctx, "user-")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		has, _ := client.WithNamespace("scope-a").Has(ctx, "user-1")
+		assert.False(t, has, "should forget keys in the namespace")
+
+		has, _ = client.WithNamespace("scope-b").Has(ctx, "user-1")
+		assert.True(t, has, "should keep keys in other namespaces")
+	})
+
+	t.Run("FlushNamespace", func(t *testing.T) {
+		flushed := client.WithNamespace("flush[1]")
+		kept := client.WithNamespace("flush-1")
+
+		_ = flushed.Put(ctx, "plain", "value", time.Minute*5)
+		_ = flushed.Tags("tag").Put(ctx, "tagged", "value", time.Minute*5)
+		_ = kept.Put(ctx, "plain", "value", time.Minute*5)
+
+		_, _ = flushed.RememberString(ctx, "remembered", time.Minute*5, func(ctx context.Context) (string, error) {
+			return "value", nil
+		}, cacher.WithStaleIfError(time.Minute))
+
+		err := flushed.FlushNamespace(ctx)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		keys, err := r.Keys(ctx, "*flush\\[1\\]*").Result()
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Empty(t, keys, "should remove every key of the namespace")
+
+		has, _ := kept.Has(ctx, "plain")
+		assert.True(t, has, "should keep keys of other namespaces")
+
+		assert.Error(t, client.FlushNamespace(ctx), "should refuse to flush without a namespace")
+	})
+
+	t.Run("VersionedNamespace", func(t *testing.T) {
+		billing := client.WithVersionedNamespace("versioned")
+
+		err := billing.Put(ctx, "invoice", "hello-world", time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		keys, err := r.Keys(ctx, "versioned:*:invoice").Result()
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Len(t, keys, 1, "should embed the generation in the key")
+
+		_ = billing.Tags("tag").Put(ctx, "tagged", "value", time.Minute*5)
+		_ = billing.WithNamespace("nested").Put(ctx, "nested", "value", time.Minute*5)
+
+		// the flush must not walk the keyspace
+		err = cacher.NewWithStore(noScanStore{cacher.NewMemoryStore()}).WithVersionedNamespace("versioned").FlushNamespace(ctx)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		err = billing.FlushNamespace(ctx)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		has, _ := billing.Has(ctx, "invoice")
+		assert.False(t, has, "should not see keys of the previous generation")
+
+		has, _ = billing.Tags("tag").Has(ctx, "tagged")
+		assert.False(t, has, "should not see tagged keys of the previous generation")
+
+		has, _ = billing.WithNamespace("nested").Has(ctx, "nested")
+		assert.False(t, has, "should not see nested keys of the previous generation")
+
+		exists, _ := r.Exists(ctx, keys[0]).Result()
+		assert.Equal(t, int64(1), exists, "should leave old keys to expire")
+
+		_ = billing.Put(ctx, "invoice", "new-value", time.Minute*5)
+
+		value, err := cacher.New(r).WithVersionedNamespace("versioned").GetString(ctx, "invoice")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "new-value", value, "should share the generation across clients")
+	})
+
+	t.Run("VersionedNamespaceLostGeneration", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		billing := cacher.NewWithStore(store).WithVersionedNamespace("lost")
+
+		_ = billing.FlushNamespace(ctx)
+		_ = billing.Put(ctx, "a", "flushed", time.Minute*5)
+
+		// the generation is evicted or removed along with the rest of the keyspace
+		_, _ = store.Del(ctx, "cacher:generation:lost:")
+
+		err := billing.FlushNamespace(ctx)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		_, err = billing.GetString(ctx, "a")
+		assert.ErrorIs(t, err, cacher.NotFoundError, "should never reuse the generation of flushed keys")
+	})
+
+	t.Run("Entity", func(t *testing.T) {
+		entities := cacher.NewEntityWithClient[TestEntity](client).WithNamespace("entities")
+
+		entity := &TestEntity{ID: "entity-id", Name: "entity-name"}
+
+		_ = entities.Put(ctx, "entity", entity, time.Minute*5)
+
+		exists, _ := r.Exists(ctx, "entities:entity").Result()
+		assert.Equal(t, int64(1), exists, "should store the entity within the namespace")
+
+		_ = entities.FlushNamespace(ctx)
+
+		_, err := entities.Get(ctx, "entity")
+		assert.ErrorIs(t, err, cacher.NotFoundError, "should flush the entities")
+	})
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	"github.com/redis/go-redis/v9"
+)
+
+// fixedWindowPrefix is the namespace holding the counters of FixedWindow limiters.
+const fixedWindowPrefix = "cacher:ratelimit:window:"
+
+// fixedWindowScript counts requests in a counter that expires with the window. It replies with allowed, remaining and
+// the time until the window ends in microseconds if the request was denied.
+var fixedWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local cost = tonumber(ARGV[3])
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+
+if count + cost > limit then
+	local retry = -1
+
+	if cost <= limit then
+		retry = redis.call("PTTL", KEYS[1]) * 1000
+	end
+
+	return {0, math.max(limit - count, 0), retry}
+end
+
+count = redis.call("INCRBY", KEYS[1], cost)
+
+if redis.call("PTTL", KEYS[1]) < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+
+return {1, limit - count, 0}
+`)
+
+// NewFixedWindow creates a limiter that allows up to limit requests per key in every window. A window starts with the
+// first request for a key.
+func NewFixedWindow(client *cacher.Client, limit int64, window time.Duration) *FixedWindow {
+	return &FixedWindow{
+		client: client,
+		limit:  limit,
+		window: max(window, time.Millisecond),
+	}
+}
+
+// FixedWindow is a Limiter that counts requests in fixed windows, see NewFixedWindow. It is the cheapest limiter, a
+// single counter per key, but lets up to twice the limit through around the end of a window. Use SlidingLog or
+// TokenBucket if that matters.
+type FixedWindow struct {
+	client *cacher.Client
+	limit  int64
+	window time.Duration
+}
+
+// Allow reports whether a single request for the key is allowed.
+func (l *FixedWindow) Allow(ctx context.Context, key string) (Result, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN reports whether n requests for the key are allowed at once. They are either all counted or none are.
+// It returns a NegativeCountError if n is negative.
+func (l *FixedWindow) AllowN(ctx context.Context, key string, n int64) (Result, error) {
+	if n < 0 {
+		return Result{}, NegativeCountError
+	}
+
+	reply, err := l.client.Run(ctx, fixedWindowScript, []string{fixedWindowPrefix + key}, l.limit, l.window.Milliseconds(), n)
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(reply)
+}
@@ -0,0 +1,108 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	"github.com/arhea/go-cacher/ratelimit"
+	mockredis "github.com/arhea/go-mock-redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixedWindow(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	mock, err := mockredis.NewClient(ctx, t)
+
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	client := cacher.New(mock.Client())
+
+	t.Run("Allow", func(t *testing.T) {
+		var limiter ratelimit.Limiter = ratelimit.NewFixedWindow(client, 3, time.Millisecond*500)
+
+		for i := int64(2); i >= 0; i-- {
+			result, err := limiter.Allow(ctx, "allow")
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			assert.True(t, result.Allowed, "should allow requests up to the limit")
+			assert.Equal(t, i, result.Remaining)
+			assert.Zero(t, result.RetryAfter)
+		}
+
+		result, _ := limiter.Allow(ctx, "allow")
+		assert.False(t, result.Allowed, "should deny requests over the limit")
+		assert.Zero(t, result.Remaining)
+		assert.Positive(t, result.RetryAfter)
+		assert.LessOrEqual(t, result.RetryAfter, time.Millisecond*500, "should retry once the window ends")
+
+		other, _ := limiter.Allow(ctx, "allow-other")
+		assert.True(t, other.Allowed, "should limit every key on its own")
+
+		time.Sleep(result.RetryAfter + time.Millisecond*100)
+
+		result, _ = limiter.Allow(ctx, "allow")
+		assert.True(t, result.Allowed, "should allow requests in the next window")
+		assert.Equal(t, int64(2), result.Remaining)
+	})
+
+	t.Run("AllowN", func(t *testing.T) {
+		limiter := ratelimit.NewFixedWindow(client, 3, time.Minute)
+
+		result, _ := limiter.AllowN(ctx, "allow-n", 2)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(1), result.Remaining)
+
+		result, _ = limiter.AllowN(ctx, "allow-n", 2)
+		assert.False(t, result.Allowed, "should deny requests that don't fit")
+		assert.Equal(t, int64(1), result.Remaining, "should not count denied requests")
+
+		result, _ = limiter.AllowN(ctx, "allow-n", 4)
+		assert.False(t, result.Allowed)
+		assert.Negative(t, result.RetryAfter, "should never allow more than the limit")
+
+		result, _ = limiter.AllowN(ctx, "allow-n", 1)
+		assert.True(t, result.Allowed)
+		assert.Zero(t, result.Remaining)
+	})
+
+	t.Run("Namespaced", func(t *testing.T) {
+		limiter := ratelimit.NewFixedWindow(client.WithNamespace("api"), 1, time.Minute)
+
+		_, _ = limiter.Allow(ctx, "namespaced")
+
+		exists, _ := mock.Client().Exists(ctx, "api:cacher:ratelimit:window:namespaced").Result()
+		assert.Equal(t, int64(1), exists, "should keep the counter within the namespace")
+	})
+
+	t.Run("MemoryStore", func(t *testing.T) {
+		limiter := ratelimit.NewFixedWindow(cacher.NewWithStore(cacher.NewMemoryStore()), 1, time.Minute)
+
+		_, err := limiter.Allow(ctx, "memory")
+		assert.ErrorIs(t, err, cacher.ScriptNotSupportedError)
+	})
+	t.Run("NegativeCount", func(t *testing.T) {
+		limiter := ratelimit.NewFixedWindow(client, 3, time.Minute)
+
+		_, err := limiter.AllowN(ctx, "negative", -5)
+		assert.ErrorIs(t, err, ratelimit.NegativeCountError, "should not let callers grant themselves requests")
+
+		exists, _ := mock.Client().Exists(ctx, "cacher:ratelimit:window:negative").Result()
+		assert.Zero(t, exists, "should not touch the limiter state")
+
+		result, _ := limiter.AllowN(ctx, "negative", 3)
+		assert.True(t, result.Allowed)
+		assert.Zero(t, result.Remaining, "should not raise the limit")
+	})
+}
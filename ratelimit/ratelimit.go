@@ -0,0 +1,67 @@
+// Package ratelimit provides distributed rate limiters built on a cacher.Client. Every limiter keeps its state in the
+// redis connection of the client and updates it with a single Lua script per request, so concurrent processes share
+// the same limits without races. Keys are namespaced like every other key of the client, use
+// cacher.Client.WithNamespace to keep limiters with different limits apart. Time is read from the redis server so the
+// clocks of the processes don't need to agree.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// NegativeCountError is returned by AllowN when asked for a negative number of requests.
+var NegativeCountError = errors.New("ratelimit: negative number of requests")
+
+// Limiter limits how often requests identified by a key are allowed. It is implemented by FixedWindow, SlidingLog and
+// TokenBucket.
+type Limiter interface {
+	// Allow reports whether a single request for the key is allowed.
+	Allow(ctx context.Context, key string) (Result, error)
+
+	// AllowN reports whether n requests for the key are allowed at once. It returns a NegativeCountError if n is
+	// negative.
+	AllowN(ctx context.Context, key string, n int64) (Result, error)
+}
+
+// Result is the outcome of a request to a Limiter.
+type Result struct {
+	// Allowed is true if the request was allowed and counted against the limit. Denied requests are not counted.
+	Allowed bool
+
+	// Remaining is how many more requests would be allowed right now.
+	Remaining int64
+
+	// RetryAfter is how long to wait before the same request would be allowed, 0 if it was allowed. It is negative if
+	// the request can never be allowed because it asks for more than the limit.
+	RetryAfter time.Duration
+}
+
+// newResult reads the {allowed, remaining, retry after in microseconds} reply of a limiter script.
+func newResult(reply any) (Result, error) {
+	values, ok := reply.([]any)
+
+	if !ok || len(values) != 3 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected script reply %v", reply)
+	}
+
+	ints := make([]int64, len(values))
+
+	for i, value := range values {
+		n, ok := value.(int64)
+
+		if !ok {
+			return Result{}, fmt.Errorf("ratelimit: unexpected script reply %v", reply)
+		}
+
+		ints[i] = n
+	}
+
+	return Result{
+		Allowed:    ints[0] == 1,
+		Remaining:  ints[1],
+		RetryAfter: time.Duration(ints[2]) * time.Microsecond,
+	}, nil
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingLogPrefix is the namespace holding the logs of SlidingLog limiters.
+const slidingLogPrefix = "cacher:ratelimit:log:"
+
+// slidingLogScript keeps a sorted set of the times of the requests within the window. It replies with allowed,
+// remaining and the time until enough requests leave the window in microseconds if the request was denied.
+var slidingLogScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+
+local count = redis.call("ZCARD", KEYS[1])
+
+if count + cost > limit then
+	local retry = -1
+
+	if cost <= limit then
+		-- the request fits once the oldest requests that take up its room leave the window
+		local index = count + cost - limit - 1
+		local oldest = redis.call("ZRANGE", KEYS[1], index, index, "WITHSCORES")
+		retry = tonumber(oldest[2]) + window - now
+	end
+
+	return {0, math.max(limit - count, 0), retry}
+end
+
+for i = 1, cost do
+	redis.call("ZADD", KEYS[1], now, string.format("%.0f", now) .. ":" .. ARGV[4] .. ":" .. i)
+end
+
+redis.call("PEXPIRE", KEYS[1], math.ceil(window / 1000))
+
+return {1, limit - count - cost, 0}
+`)
+
+// NewSlidingLog creates a limiter that allows up to limit requests per key within any window of time.
+func NewSlidingLog(client *cacher.Client, limit int64, window time.Duration) *SlidingLog {
+	return &SlidingLog{
+		client: client,
+		limit:  limit,
+		window: max(window, time.Millisecond),
+	}
+}
+
+// SlidingLog is a Limiter that logs the time of every request, see NewSlidingLog. It enforces the limit exactly over
+// any window but stores an entry per allowed request, so it suits low limits such as login attempts. Use TokenBucket
+// for high limits.
+type SlidingLog struct {
+	client *cacher.Client
+	limit  int64
+	window time.Duration
+}
+
+// Allow reports whether a single request for the key is allowed.
+func (l *SlidingLog) Allow(ctx context.Context, key string) (Result, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN reports whether n requests for the key are allowed at once. They are either all logged or none are.
+// It returns a NegativeCountError if n is negative.
+func (l *SlidingLog) AllowN(ctx context.Context, key string, n int64) (Result, error) {
+	if n < 0 {
+		return Result{}, NegativeCountError
+	}
+
+	// requests logged in the same microsecond by different processes need distinct members
+	id := make([]byte, 8)
+
+	if _, err := rand.Read(id); err != nil {
+		return Result{}, err
+	}
+
+	reply, err := l.client.Run(ctx, slidingLogScript, []string{slidingLogPrefix + key}, l.limit, l.window.Microseconds(), n, hex.EncodeToString(id))
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(reply)
+}
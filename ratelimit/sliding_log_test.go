@@ -0,0 +1,119 @@
+package ratelimit_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	"github.com/arhea/go-cacher/ratelimit"
+	mockredis "github.com/arhea/go-mock-redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlidingLog(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	mock, err := mockredis.NewClient(ctx, t)
+
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	client := cacher.New(mock.Client())
+
+	t.Run("Allow", func(t *testing.T) {
+		var limiter ratelimit.Limiter = ratelimit.NewSlidingLog(client, 2, time.Millisecond*600)
+
+		first, err := limiter.Allow(ctx, "allow")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, first.Allowed)
+		assert.Equal(t, int64(1), first.Remaining)
+
+		time.Sleep(time.Millisecond * 300)
+
+		second, _ := limiter.Allow(ctx, "allow")
+		assert.True(t, second.Allowed)
+		assert.Zero(t, second.Remaining)
+
+		result, _ := limiter.Allow(ctx, "allow")
+		assert.False(t, result.Allowed, "should deny requests over the limit")
+		assert.Positive(t, result.RetryAfter)
+		assert.LessOrEqual(t, result.RetryAfter, time.Millisecond*300, "should retry once the first request leaves the window")
+
+		time.Sleep(result.RetryAfter + time.Millisecond*50)
+
+		result, _ = limiter.Allow(ctx, "allow")
+		assert.True(t, result.Allowed, "should allow a request once the first leaves the window")
+		assert.Zero(t, result.Remaining, "should still count the second request")
+	})
+
+	t.Run("AllowN", func(t *testing.T) {
+		limiter := ratelimit.NewSlidingLog(client, 3, time.Minute)
+
+		result, _ := limiter.AllowN(ctx, "allow-n", 2)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(1), result.Remaining)
+
+		result, _ = limiter.AllowN(ctx, "allow-n", 2)
+		assert.False(t, result.Allowed, "should deny requests that don't fit")
+		assert.Equal(t, int64(1), result.Remaining, "should not log denied requests")
+		assert.Positive(t, result.RetryAfter)
+
+		result, _ = limiter.AllowN(ctx, "allow-n", 4)
+		assert.False(t, result.Allowed)
+		assert.Negative(t, result.RetryAfter, "should never allow more than the limit")
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		limiter := ratelimit.NewSlidingLog(client, 10, time.Minute)
+
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			allowed int
+		)
+
+		for i := 0; i < 25; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				result, err := limiter.Allow(ctx, "concurrent")
+
+				if err == nil && result.Allowed {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		assert.Equal(t, 10, allowed, "should allow exactly the limit across concurrent requests")
+	})
+	t.Run("NegativeCount", func(t *testing.T) {
+		limiter := ratelimit.NewSlidingLog(client, 3, time.Minute)
+
+		_, err := limiter.AllowN(ctx, "negative", -5)
+		assert.ErrorIs(t, err, ratelimit.NegativeCountError, "should not let callers grant themselves requests")
+
+		exists, _ := mock.Client().Exists(ctx, "cacher:ratelimit:log:negative").Result()
+		assert.Zero(t, exists, "should not touch the limiter state")
+
+		result, _ := limiter.AllowN(ctx, "negative", 3)
+		assert.True(t, result.Allowed)
+		assert.Zero(t, result.Remaining, "should not raise the limit")
+	})
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketPrefix is the namespace holding the state of TokenBucket limiters.
+const tokenBucketPrefix = "cacher:ratelimit:bucket:"
+
+// tokenBucketScript implements the generic cell rate algorithm. It stores the theoretical arrival time of the next
+// request, which is when the bucket will be full again, in microseconds. It replies with allowed, remaining and the
+// time until enough tokens are refilled in microseconds if the request was denied.
+var tokenBucketScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local tolerance = burst * interval
+local tat = math.max(tonumber(redis.call("GET", KEYS[1]) or "0"), now)
+local remaining = math.max(math.floor((now - tat + tolerance) / interval), 0)
+
+if cost > burst then
+	return {0, remaining, -1}
+end
+
+local next = tat + cost * interval
+local allowAt = next - tolerance
+
+if now < allowAt then
+	return {0, remaining, math.ceil(allowAt - now)}
+end
+
+redis.call("SET", KEYS[1], string.format("%.0f", next), "PX", math.max(math.ceil((next - now) / 1000), 1))
+
+return {1, math.max(math.floor((now - next + tolerance) / interval), 0), 0}
+`)
+
+// NewTokenBucket creates a limiter that allows bursts of up to burst requests per key and refills at rate requests
+// every period, so NewTokenBucket(client, 10, time.Second, 20) allows 20 requests at once and then 10 a second.
+func NewTokenBucket(client *cacher.Client, rate int64, period time.Duration, burst int64) *TokenBucket {
+	return &TokenBucket{
+		client:   client,
+		interval: float64(period.Microseconds()) / float64(max(rate, 1)),
+		burst:    burst,
+	}
+}
+
+// TokenBucket is a Limiter that refills a bucket of tokens at a steady rate, see NewTokenBucket. It is implemented with
+// the generic cell rate algorithm, which stores a single timestamp per key instead of a count of tokens and a refill
+// time, so it is as cheap as FixedWindow while spreading requests out evenly.
+type TokenBucket struct {
+	client   *cacher.Client
+	interval float64
+	burst    int64
+}
+
+// Allow reports whether a single request for the key is allowed.
+func (l *TokenBucket) Allow(ctx context.Context, key string) (Result, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN reports whether n requests for the key are allowed at once. They either all take a token or none do.
+// It returns a NegativeCountError if n is negative.
+func (l *TokenBucket) AllowN(ctx context.Context, key string, n int64) (Result, error) {
+	if n < 0 {
+		return Result{}, NegativeCountError
+	}
+
+	reply, err := l.client.Run(ctx, tokenBucketScript, []string{tokenBucketPrefix + key}, l.interval, l.burst, n)
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(reply)
+}
@@ -0,0 +1,98 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	"github.com/arhea/go-cacher/ratelimit"
+	mockredis "github.com/arhea/go-mock-redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	mock, err := mockredis.NewClient(ctx, t)
+
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	client := cacher.New(mock.Client())
+
+	t.Run("Allow", func(t *testing.T) {
+		// a burst of 3 refilled at a token every 200ms
+		var limiter ratelimit.Limiter = ratelimit.NewTokenBucket(client, 5, time.Second, 3)
+
+		for i := int64(2); i >= 0; i-- {
+			result, err := limiter.Allow(ctx, "allow")
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			assert.True(t, result.Allowed, "should allow a burst")
+			assert.Equal(t, i, result.Remaining)
+		}
+
+		result, _ := limiter.Allow(ctx, "allow")
+		assert.False(t, result.Allowed, "should deny requests once the bucket is empty")
+		assert.Positive(t, result.RetryAfter)
+		assert.LessOrEqual(t, result.RetryAfter, time.Millisecond*200, "should retry once a token is refilled")
+
+		time.Sleep(result.RetryAfter + time.Millisecond*20)
+
+		result, _ = limiter.Allow(ctx, "allow")
+		assert.True(t, result.Allowed, "should allow a request once a token is refilled")
+		assert.Zero(t, result.Remaining)
+
+		result, _ = limiter.Allow(ctx, "allow")
+		assert.False(t, result.Allowed, "should not refill more than the rate")
+	})
+
+	t.Run("AllowN", func(t *testing.T) {
+		limiter := ratelimit.NewTokenBucket(client, 1, time.Minute, 3)
+
+		result, _ := limiter.AllowN(ctx, "allow-n", 2)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(1), result.Remaining)
+
+		result, _ = limiter.AllowN(ctx, "allow-n", 2)
+		assert.False(t, result.Allowed, "should deny requests that don't fit")
+		assert.Equal(t, int64(1), result.Remaining, "should not take tokens for denied requests")
+		assert.Greater(t, result.RetryAfter, time.Second*55, "should retry once enough tokens are refilled")
+
+		result, _ = limiter.AllowN(ctx, "allow-n", 4)
+		assert.False(t, result.Allowed)
+		assert.Negative(t, result.RetryAfter, "should never allow more than the burst")
+	})
+
+	t.Run("Expires", func(t *testing.T) {
+		limiter := ratelimit.NewTokenBucket(client, 10, time.Second, 2)
+
+		_, _ = limiter.AllowN(ctx, "expires", 2)
+
+		ttl, _ := mock.Client().PTTL(ctx, "cacher:ratelimit:bucket:expires").Result()
+		assert.Positive(t, ttl)
+		assert.LessOrEqual(t, ttl, time.Millisecond*200, "should expire once the bucket is full again")
+	})
+	t.Run("NegativeCount", func(t *testing.T) {
+		limiter := ratelimit.NewTokenBucket(client, 1, time.Minute, 3)
+
+		_, err := limiter.AllowN(ctx, "negative", -5)
+		assert.ErrorIs(t, err, ratelimit.NegativeCountError, "should not let callers grant themselves requests")
+
+		exists, _ := mock.Client().Exists(ctx, "cacher:ratelimit:bucket:negative").Result()
+		assert.Zero(t, exists, "should not touch the limiter state")
+
+		result, _ := limiter.AllowN(ctx, "negative", 3)
+		assert.True(t, result.Allowed)
+		assert.Zero(t, result.Remaining, "should not raise the limit")
+	})
+}
@@ -0,0 +1,274 @@
+package cacher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// raiseFenceScript raises the fencing counter of a lock to at least the given token if the lock is still held by the
+// owner.
+var raiseFenceScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	if tonumber(redis.call("GET", KEYS[2]) or "0") < tonumber(ARGV[2]) then
+		redis.call("SET", KEYS[2], ARGV[2])
+	end
+
+	return 1
+end
+
+return 0
+`)
+
+// redlockDriftFactor is the share of the ttl allowed for the clocks of the nodes drifting apart.
+const redlockDriftFactor = 0.01
+
+// RedlockOption configures a Redlock.
+type RedlockOption func(r *Redlock)
+
+// WithNodeTimeout sets how long each node is given to answer a single command. It should be small compared to the ttl
+// of the locks so an unreachable node does not use up their validity. The default is a tenth of the ttl.
+func WithNodeTimeout(timeout time.Duration) RedlockOption {
+	return func(r *Redlock) {
+		r.nodeTimeout = timeout
+	}
+}
+
+// NewRedlock creates a Redlock over independent redis nodes. The nodes must not be replicas of each other, use an odd
+// number of them, typically 3 or 5, so a majority survives the failure of the others. This will not close the redis
+// clients.
+func NewRedlock(clients []*redis.Client, opts ...RedlockOption) *Redlock {
+	r := &Redlock{
+		stores: make([]*RedisStore, 0, len(clients)),
+	}
+
+	for _, client := range clients {
+		r.stores = append(r.stores, NewRedisStore(client))
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Redlock creates locks that are held on a majority of independent redis nodes following the Redlock algorithm, so a
+// lock survives the failure of a minority of the nodes. See NewRedlock.
+type Redlock struct {
+	stores      []*RedisStore
+	nodeTimeout time.Duration
+}
+
+// Lock returns a lock named name that expires ttl after it is acquired or extended. It implements the same Locker
+// interface as the single node Lock.
+func (r *Redlock) Lock(name string, ttl time.Duration, opts ...LockOption) *RedlockLock {
+	tag := "{" + name + "}"
+
+	return &RedlockLock{
+		redlock:  r,
+		key:      mutexPrefix + tag,
+		fenceKey: fencePrefix + tag,
+		ttl:      ttl,
+		renew:    newLockOptions(opts).renew,
+	}
+}
+
+// quorum is the number of nodes a lock must be held on.
+func (r *Redlock) quorum() int {
+	return len(r.stores)/2 + 1
+}
+
+// each calls fn on every node concurrently, each call limited to the node timeout. It returns how many calls returned
+// true and the errors of the calls that failed.
+func (r *Redlock) each(ctx context.Context, ttl time.Duration, fn func(ctx context.Context, store *RedisStore) (bool, error)) (int, []error) {
+	timeout := r.nodeTimeout
+
+	if timeout <= 0 {
+		timeout = ttl / 10
+	}
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		count int
+		errs  []error
+	)
+
+	for _, store := range r.stores {
+		wg.Add(1)
+
+		go func(store *RedisStore) {
+			defer wg.Done()
+
+			nodeCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			ok, err := fn(nodeCtx, store)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, err)
+			} else if ok {
+				count++
+			}
+		}(store)
+	}
+
+	wg.Wait()
+
+	return count, errs
+}
+
+// RedlockLock is a lock held on a majority of the nodes of a Redlock, see Redlock.Lock. A lock is only considered
+// acquired or extended if a majority of the nodes agreed before its ttl, less an allowance for clock drift between the
+// nodes, ran out.
+//
+// Fencing tokens are the largest counter of the nodes the lock was acquired on. The counters of a majority of nodes are
+// raised to the token before the lock is considered acquired, so any later acquisition sees at least one of them and
+// is handed a larger token.
+type RedlockLock struct {
+	holding
+
+	redlock  *Redlock
+	key      string
+	fenceKey string
+	ttl      time.Duration
+	renew    float64
+}
+
+// TryAcquire attempts to take the lock on every node once without waiting. It returns true if the lock was acquired on
+// a majority of the nodes in time, otherwise it releases the nodes it did get. It only returns an error if too many
+// nodes failed for a majority to be reached.
+func (l *RedlockLock) TryAcquire(ctx context.Context) (bool, error) {
+	r := l.redlock
+
+	token, err := newToken()
+
+	if err != nil {
+		return false, err
+	}
+
+	start := time.Now()
+
+	var (
+		mu    sync.Mutex
+		fence int64
+	)
+
+	acquired, errs := r.each(ctx, l.ttl, func(ctx context.Context, store *RedisStore) (bool, error) {
+		ok, counter, err := store.SetNXAndIncr(ctx, l.key, []byte(token), l.ttl, l.fenceKey)
+
+		if ok {
+			mu.Lock()
+			fence = max(fence, counter)
+			mu.Unlock()
+		}
+
+		return ok, err
+	})
+
+	if acquired >= r.quorum() {
+		raised, _ := r.each(ctx, l.ttl, func(ctx context.Context, store *RedisStore) (bool, error) {
+			return raiseFenceScript.Run(ctx, store.redis, []string{l.key, l.fenceKey}, token, fence).Bool()
+		})
+
+		if raised >= r.quorum() && validity(l.ttl, start) > 0 {
+			l.hold(ctx, token, fence, time.Now().Add(validity(l.ttl, start)), l.ttl, l.renew, l.Extend)
+			return true, nil
+		}
+	}
+
+	// give back the nodes we did get so others do not have to wait for them to expire
+	r.each(context.WithoutCancel(ctx), l.ttl, func(ctx context.Context, store *RedisStore) (bool, error) {
+		return store.CompareAndDelete(ctx, l.key, []byte(token))
+	})
+
+	if len(errs) > len(r.stores)-r.quorum() {
+		return false, errors.Join(errs...)
+	}
+
+	return false, nil
+}
+
+// Acquire waits until the lock is acquired or the context is done, in which case it returns the context error.
+func (l *RedlockLock) Acquire(ctx context.Context) error {
+	return acquire(ctx, l.TryAcquire)
+}
+
+// Block waits up to wait for the lock to be acquired. It returns a LockTimeoutError if it was not acquired in time.
+func (l *RedlockLock) Block(ctx context.Context, wait time.Duration) error {
+	return block(ctx, wait, l.TryAcquire)
+}
+
+// Release releases the lock on every node. It returns a LockNotHeldError if the lock was not held on a majority of
+// the nodes.
+func (l *RedlockLock) Release(ctx context.Context) error {
+	token := l.drop()
+
+	if token == "" {
+		return LockNotHeldError
+	}
+
+	released, errs := l.redlock.each(ctx, l.ttl, func(ctx context.Context, store *RedisStore) (bool, error) {
+		return store.CompareAndDelete(ctx, l.key, []byte(token))
+	})
+
+	return l.result(released, errs)
+}
+
+// Extend resets the expiration of the lock to ttl on every node. It returns a LockNotHeldError if the lock was not
+// extended on a majority of the nodes before the new ttl ran out.
+func (l *RedlockLock) Extend(ctx context.Context, ttl time.Duration) error {
+	token := l.current()
+
+	if token == "" {
+		return LockNotHeldError
+	}
+
+	start := time.Now()
+
+	extended, errs := l.redlock.each(ctx, ttl, func(ctx context.Context, store *RedisStore) (bool, error) {
+		return store.CompareAndExpire(ctx, l.key, []byte(token), ttl)
+	})
+
+	remaining := validity(ttl, start)
+
+	if extended >= l.redlock.quorum() && remaining <= 0 {
+		return LockNotHeldError
+	}
+
+	if err := l.result(extended, errs); err != nil {
+		return err
+	}
+
+	l.extended(token, time.Now().Add(remaining))
+
+	return nil
+}
+
+// result reports whether a command succeeded on a majority of the nodes. Errors are only returned if too many nodes
+// failed for a majority to be reached.
+func (l *RedlockLock) result(succeeded int, errs []error) error {
+	if succeeded >= l.redlock.quorum() {
+		return nil
+	}
+
+	if len(errs) > len(l.redlock.stores)-l.redlock.quorum() {
+		return errors.Join(errs...)
+	}
+
+	return LockNotHeldError
+}
+
+// validity returns how long a lock with the given ttl set on the nodes from start remains valid. It allows for the
+// clocks of the nodes drifting apart plus 2ms for the resolution of redis expirations.
+func validity(ttl time.Duration, start time.Time) time.Duration {
+	drift := time.Duration(float64(ttl)*redlockDriftFactor) + time.Millisecond*2
+	return ttl - time.Since(start) - drift
+}
@@ -0,0 +1,173 @@
+package cacher_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	mockredis "github.com/arhea/go-mock-redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedlock(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	clients := make([]*redis.Client, 0, 3)
+
+	for i := 0; i < 3; i++ {
+		mock, err := mockredis.NewClient(ctx, t)
+
+		if err != nil {
+			t.Fatal(err)
+			return
+		}
+
+		clients = append(clients, mock.Client())
+	}
+
+	// a node that never answers
+	down := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: time.Millisecond * 50})
+
+	t.Cleanup(func() {
+		_ = down.Close()
+	})
+
+	redlock := cacher.NewRedlock(clients)
+
+	t.Run("Exclusive", func(t *testing.T) {
+		var lock1, lock2 cacher.Locker = redlock.Lock("exclusive", time.Second*5), redlock.Lock("exclusive", time.Second*5)
+
+		acquired, err := lock1.TryAcquire(ctx)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, acquired, "should acquire the free lock")
+
+		for _, client := range clients {
+			exists, _ := client.Exists(ctx, "cacher:mutex:{exclusive}").Result()
+			assert.Equal(t, int64(1), exists, "should hold the lock on every node")
+		}
+
+		acquired, _ = lock2.TryAcquire(ctx)
+		assert.False(t, acquired, "should not acquire a held lock")
+
+		assert.ErrorIs(t, lock2.Block(ctx, time.Millisecond*200), cacher.LockTimeoutError)
+		assert.ErrorIs(t, lock2.Release(ctx), cacher.LockNotHeldError)
+
+		assert.NoError(t, lock1.Extend(ctx, time.Second*10))
+		assert.NoError(t, lock1.Release(ctx))
+
+		acquired, _ = lock2.TryAcquire(ctx)
+		assert.True(t, acquired, "should acquire the released lock")
+
+		assert.NoError(t, lock2.Release(ctx))
+	})
+
+	t.Run("MinorityDown", func(t *testing.T) {
+		redlock := cacher.NewRedlock([]*redis.Client{clients[0], clients[1], down})
+
+		lock := redlock.Lock("minority-down", time.Second*5)
+
+		acquired, err := lock.TryAcquire(ctx)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, acquired, "should acquire with a majority of the nodes")
+		assert.NoError(t, lock.Extend(ctx, time.Second*5))
+		assert.NoError(t, lock.Release(ctx))
+	})
+
+	t.Run("MajorityDown", func(t *testing.T) {
+		redlock := cacher.NewRedlock([]*redis.Client{clients[0], down, down})
+
+		lock := redlock.Lock("majority-down", time.Second*5)
+
+		acquired, err := lock.TryAcquire(ctx)
+
+		assert.Error(t, err, "should report that a majority can't be reached")
+		assert.False(t, acquired)
+
+		exists, _ := clients[0].Exists(ctx, "cacher:mutex:{majority-down}").Result()
+		assert.Zero(t, exists, "should give back the nodes it did get")
+	})
+
+	t.Run("Contended", func(t *testing.T) {
+		// another process holds the lock on two of the three nodes
+		_ = clients[1].Set(ctx, "cacher:mutex:{contended}", "other", time.Minute).Err()
+		_ = clients[2].Set(ctx, "cacher:mutex:{contended}", "other", time.Minute).Err()
+
+		lock := redlock.Lock("contended", time.Second*5)
+
+		acquired, err := lock.TryAcquire(ctx)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.False(t, acquired, "should not acquire without a majority")
+
+		exists, _ := clients[0].Exists(ctx, "cacher:mutex:{contended}").Result()
+		assert.Zero(t, exists, "should give back the node it did get")
+	})
+
+	t.Run("FencingToken", func(t *testing.T) {
+		// one node has handed out many more tokens than the others
+		_ = clients[0].Set(ctx, "cacher:fence:{fencing}", 100, 0).Err()
+
+		lock := redlock.Lock("fencing", time.Second*5)
+
+		_ = lock.Acquire(ctx)
+
+		first := lock.FencingToken()
+		assert.Equal(t, int64(101), first, "should use the largest counter")
+
+		_ = lock.Release(ctx)
+
+		// the next acquisition only reaches the nodes that were behind
+		redlock := cacher.NewRedlock([]*redis.Client{down, clients[1], clients[2]})
+
+		next := redlock.Lock("fencing", time.Second*5)
+
+		_ = next.Acquire(ctx)
+		assert.Greater(t, next.FencingToken(), first, "should hand out larger tokens on any majority")
+
+		_ = next.Release(ctx)
+	})
+	t.Run("AutoRenew", func(t *testing.T) {
+		lock := redlock.Lock("auto-renew", time.Millisecond*300, cacher.WithAutoRenew(0.3))
+
+		_ = lock.Acquire(ctx)
+
+		time.Sleep(time.Millisecond * 800)
+
+		acquired, _ := redlock.Lock("auto-renew", time.Minute).TryAcquire(ctx)
+		assert.False(t, acquired, "should be held past its ttl")
+		assert.NoError(t, lock.Context().Err())
+
+		// a majority of the nodes lose the lock
+		_ = clients[0].Del(ctx, "cacher:mutex:{auto-renew}").Err()
+		_ = clients[1].Del(ctx, "cacher:mutex:{auto-renew}").Err()
+
+		lockCtx := lock.Context()
+
+		select {
+		case <-lockCtx.Done():
+		case <-time.After(time.Second * 2):
+			t.Error("should cancel the context when the lock can't be extended")
+			return
+		}
+
+		assert.ErrorIs(t, context.Cause(lockCtx), cacher.LockNotHeldError)
+	})
+}
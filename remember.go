@@ -0,0 +1,388 @@
+package cacher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RememberOption configures how a Remember function fetches a value that is not in the cache.
+type RememberOption func(o *rememberOptions)
+
+type rememberOptions struct {
+	lockTTL        time.Duration
+	lockWait       time.Duration
+	xfetchBeta     float64
+	staleGrace     time.Duration
+	onRefreshError func(key string, err error)
+	staleWindow    time.Duration
+	negativeTTL    time.Duration
+	fetchOnError   bool
+}
+
+// WithLock takes a lock in the cache before calling the fetcher on a miss so only one process recomputes the value.
+// The lock expires after ttl in case its holder dies. Processes that do not get the lock poll the cache for the value
+// for up to wait before giving up and calling the fetcher themselves, with a wait of 0 they do so straight away.
+func WithLock(ttl time.Duration, wait time.Duration) RememberOption {
+	return func(o *rememberOptions) {
+		o.lockTTL = ttl
+		o.lockWait = wait
+	}
+}
+
+// WithXFetch recomputes a value before it expires with a probability that rises as the expiration approaches, weighted
+// by how long the fetcher took to compute it. This spreads recomputation of hot keys out instead of every caller
+// missing at once when they expire. A beta of 1 is a good default, larger values recompute earlier. The time the
+// fetcher took and the expiration are stored alongside the value, Get and the other read functions return the value
+// without them. It has no effect on values stored without an expiration.
+func WithXFetch(beta float64) RememberOption {
+	return func(o *rememberOptions) {
+		o.xfetchBeta = beta
+	}
+}
+
+// WithStaleWhileRevalidate keeps serving a value for up to grace after it expires while the fetcher refreshes it in the
+// background. The expiration passed to Remember becomes a soft TTL after which the value is stale, the value is only
+// removed from the cache once the hard TTL of the expiration plus grace has passed. Callers receiving a stale value
+// never see refresh errors, use WithRefreshErrorHandler to be told about them. A background refresh is given at most
+// grace to complete. It has no effect on values stored without an expiration.
+func WithStaleWhileRevalidate(grace time.Duration) RememberOption {
+	return func(o *rememberOptions) {
+		o.staleGrace = grace
+	}
+}
+
+// WithRefreshErrorHandler sets a function called with the errors of background refreshes started by
+// WithStaleWhileRevalidate. It is called from the refreshing goroutine.
+func WithRefreshErrorHandler(fn func(key string, err error)) RememberOption {
+	return func(o *rememberOptions) {
+		o.onRefreshError = fn
+	}
+}
+
+// WithStaleIfError keeps a shadow copy of the value for window after it expires. If the fetcher fails within that
+// window the shadow copy is returned along with an error wrapping both StaleValueError and the fetcher error, so
+// callers that can live with an old value check for StaleValueError and use it. It has no effect on values stored
+// without an expiration. The shadow copy is the value itself kept past its expiration, so the Get functions report it
+// as not found but Has reports the key as present until the window ends.
+func WithStaleIfError(window time.Duration) RememberOption {
+	return func(o *rememberOptions) {
+		o.staleWindow = window
+	}
+}
+
+// WithNegativeTTL caches the absence of a value. When the fetcher returns an error wrapping NotFoundError a tombstone
+// is stored for ttl, until it expires Remember and the Get functions return NotFoundError without calling the
+// fetcher. Use a ttl shorter than the one of the value so newly created values are picked up quickly. Has reports a
+// key holding a tombstone as present.
+func WithNegativeTTL(ttl time.Duration) RememberOption {
+	return func(o *rememberOptions) {
+		o.negativeTTL = ttl
+	}
+}
+
+// fetchOnError treats every error reading the cache as a miss. The entity client has always called the fetcher when the
+// cached value could not be read.
+func fetchOnError() RememberOption {
+	return func(o *rememberOptions) {
+		o.fetchOnError = true
+	}
+}
+
+func newRememberOptions(opts []RememberOption) *rememberOptions {
+	o := &rememberOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// stale reports whether a cached entry is past its soft TTL and should be refreshed in the background.
+func (o *rememberOptions) stale(e *entry) bool {
+	return o.staleGrace > 0 && !e.expiresAt.IsZero() && !time.Now().Before(e.expiresAt)
+}
+
+// expiresEarly decides whether a cached entry should be recomputed before it expires following the XFetch algorithm.
+func (o *rememberOptions) expiresEarly(e *entry) bool {
+	if o.xfetchBeta <= 0 || e.expiresAt.IsZero() {
+		return false
+	}
+
+	// 1 - rand.Float64() is in (0, 1] so the logarithm is never infinite
+	gap := time.Duration(-float64(e.delta) * o.xfetchBeta * math.Log(1-rand.Float64()))
+
+	return !time.Now().Add(gap).Before(e.expiresAt)
+}
+
+// lockPrefix is the reserved namespace the locks guarding recomputation live in so they never collide with cached
+// keys or get removed by ForgetWithPrefix.
+const lockPrefix = "cacher:lock:"
+
+// remember implements the read through logic shared by every Remember function. It returns the cached value whenever
+// the key exists, zero values such as empty strings are cached like any other. Otherwise it calls the fetcher and puts
+// the result in the cache. Concurrent misses for the same key within the process share a single call to the fetcher.
+func remember[T any](ctx context.Context, c *Client, key string, exp time.Duration, decode func(data []byte) (T, error), encode func(val T) ([]byte, error), fetcher func(ctx context.Context) (T, error), opts []RememberOption) (T, error) {
+	var zero T
+
+	o := newRememberOptions(opts)
+
+	// the value lives under the key resolved by the client while refresh errors report the key given by the caller
+	name := key
+
+	key, err := c.key(ctx, key)
+
+	if err != nil {
+		return zero, err
+	}
+
+	// lookup returns the cached value and its entry, found is false if it is missing or can not be decrypted or
+	// decoded. A tombstone is found along with a NotFoundError.
+	lookup := func(ctx context.Context) (T, *entry, bool, error) {
+		data, err := c.read(ctx, key)
+
+		if err == nil {
+			e := unmarshalEntry(data)
+
+			// an expired value only kept as a shadow copy is a miss
+			if e.shadowed() {
+				return zero, nil, false, nil
+			}
+
+			if e.tombstone {
+				return zero, e, true, NotFoundError
+			}
+
+			var val T
+
+			val, err = decode(e.value)
+
+			// if there was no error the key exists so return
+			if err == nil {
+				return val, e, true, nil
+			}
+		}
+
+		// if there was an error and it wasn't a miss or a value we can't decrypt return
+		if err != nil && !o.fetchOnError && !errors.Is(err, NotFoundError) && !errors.Is(err, DecryptionError) {
+			return zero, nil, false, err
+		}
+
+		return zero, nil, false, nil
+	}
+
+	fetch := func(ctx context.Context) (T, error) {
+		start := time.Now()
+
+		// call the fetcher to get the value we should remember
+		val, err := fetcher(ctx)
+
+		// remember that there is nothing to fetch
+		if errors.Is(err, NotFoundError) && o.negativeTTL > 0 {
+			if err := c.write(ctx, key, (&entry{tombstone: true}).marshal(), o.negativeTTL); err != nil {
+				return zero, err
+			}
+		}
+
+		if err != nil {
+			return zero, err
+		}
+
+		data, err := encode(val)
+
+		if err != nil {
+			return zero, err
+		}
+
+		ttl := exp
+
+		// keep how long the fetch took and when the value expires so it can be recomputed in time
+		if (o.xfetchBeta > 0 || o.staleGrace > 0 || o.staleWindow > 0) && exp > 0 {
+			e := &entry{value: data, delta: time.Since(start), expiresAt: start.Add(exp)}
+			ttl += o.staleGrace
+
+			// keep the value past its expiration as a shadow copy to fall back to if the fetcher fails
+			if o.staleWindow > 0 {
+				e.shadowAt = start.Add(ttl)
+				ttl += o.staleWindow
+			}
+
+			data = e.marshal()
+		}
+
+		// put the value in the cache for later
+		if err := c.write(ctx, key, data, ttl); err != nil {
+			return zero, err
+		}
+
+		return val, nil
+	}
+
+	// shadow returns the shadow copy kept by WithStaleIfError
+	shadow := func(ctx context.Context) (T, bool) {
+		data, err := c.read(ctx, key)
+
+		if err != nil {
+			return zero, false
+		}
+
+		e := unmarshalEntry(data)
+
+		if e.tombstone || e.shadowAt.IsZero() {
+			return zero, false
+		}
+
+		val, err := decode(e.value)
+
+		return val, err == nil
+	}
+
+	// attempt to fetch the value from the cache
+	val, current, found, err := lookup(ctx)
+
+	if err != nil {
+		return zero, err
+	}
+
+	// cached returns the value this caller is recomputing early or refreshing, if any
+	cached := func() (T, bool) {
+		return val, found
+	}
+
+	// refreshed returns a value or tombstone stored since this caller decided to call the fetcher
+	refreshed := func(ctx context.Context) (T, bool, error) {
+		val, e, found, err := lookup(ctx)
+
+		if !found || (current != nil && e.sameAs(current)) {
+			return zero, false, err
+		}
+
+		return val, true, err
+	}
+
+	run := func(ctx context.Context) (T, error) {
+		return load(ctx, c, key, func(ctx context.Context) (T, error) {
+			if o.lockTTL <= 0 {
+				return fetch(ctx)
+			}
+
+			return fetchLocked(ctx, c, key, o, cached, refreshed, fetch)
+		})
+	}
+
+	if found && o.stale(current) {
+		go refresh(ctx, name, o, run)
+		return val, nil
+	}
+
+	if found && !o.expiresEarly(current) {
+		return val, nil
+	}
+
+	result, err := run(ctx)
+
+	// fall back to the shadow copy unless the caller gave up or the value no longer exists
+	if err != nil && o.staleWindow > 0 && ctx.Err() == nil && !errors.Is(err, NotFoundError) {
+		if stale, ok := shadow(ctx); ok {
+			return stale, errors.Join(StaleValueError, err)
+		}
+	}
+
+	return result, err
+}
+
+// refresh calls run in the background for a caller that has already been given a stale value. It is not cancelled
+// with the caller's context but is given at most the grace period to complete.
+func refresh[T any](ctx context.Context, key string, o *rememberOptions, run func(ctx context.Context) (T, error)) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), o.staleGrace)
+	defer cancel()
+
+	if _, err := run(ctx); err != nil && o.onRefreshError != nil {
+		o.onRefreshError(key, err)
+	}
+}
+
+// fetchLocked calls fetch while holding a lock in the cache so only one process recomputes the key. Processes that
+// lose the race return the value being recomputed early or refreshed if there is one, otherwise they poll refreshed until the value
+// appears or take over the lock if it is released without one. Errors while polling are ignored, once the wait runs
+// out they call fetch without the lock.
+func fetchLocked[T any](ctx context.Context, c *Client, key string, o *rememberOptions, cached func() (T, bool), refreshed func(ctx context.Context) (T, bool, error), fetch func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+
+	token, err := newToken()
+
+	if err != nil {
+		return zero, err
+	}
+
+	lockKey := lockPrefix + key
+	deadline := time.Now().Add(o.lockWait)
+	interval := min(o.lockWait/10+time.Millisecond, time.Millisecond*50)
+
+	// always try for the lock at least once so a wait of 0 still keeps other processes from fetching
+	for {
+		acquired, err := c.store.SetNX(ctx, lockKey, []byte(token), o.lockTTL)
+
+		if err == nil && acquired {
+			defer func() {
+				_, _ = c.store.CompareAndDelete(context.WithoutCancel(ctx), lockKey, []byte(token))
+			}()
+
+			// the previous holder may have filled the cache just before we took the lock
+			if val, found, err := refreshed(ctx); found || err != nil {
+				return val, err
+			}
+
+			return fetch(ctx)
+		}
+
+		// another process is already recomputing a value that has not expired yet
+		if val, found := cached(); found {
+			return val, nil
+		}
+
+		if !time.Now().Before(deadline) {
+			break
+		}
+
+		timer := time.NewTimer(interval)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return zero, ctx.Err()
+		case <-timer.C:
+		}
+
+		if val, found, err := refreshed(ctx); found {
+			return val, err
+		}
+	}
+
+	// waited long enough, recompute without the lock
+	return fetch(ctx)
+}
+
+// load calls fn at most once at a time per key and type, every concurrent caller waits for and receives the same
+// result. A caller giving up does not affect the others, the call keeps running until the latest deadline of its callers
+// and is only cancelled once none of them are waiting.
+func load[T any](ctx context.Context, c *Client, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+
+	// include the type in the key so callers reading the same key as different types never share a result
+	flightKey := fmt.Sprintf("%T:%s", zero, key)
+
+	val, err := c.flight.do(ctx, flightKey, func(ctx context.Context) (interface{}, error) {
+		return fn(ctx)
+	})
+
+	if err != nil {
+		return zero, err
+	}
+
+	return val.(T), nil
+}
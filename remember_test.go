@@ -0,0 +1,591 @@
+package cacher_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemember(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("CoalescesConcurrentMisses", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+
+		var calls atomic.Int32
+		var wg sync.WaitGroup
+
+		results := make([]string, 20)
+
+		for i := range results {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				results[i], _ = client.RememberString(ctx, "stampede", time.Minute*5, func(ctx context.Context) (string, error) {
+					calls.Add(1)
+					time.Sleep(time.Millisecond * 100)
+
+					return "hello-world", nil
+				})
+			}(i)
+		}
+
+		wg.Wait()
+
+		assert.Equal(t, int32(1), calls.Load(), "should only call the fetcher once")
+
+		for _, result := range results {
+			assert.Equal(t, "hello-world", result, "every caller should receive the fetched value")
+		}
+	})
+
+	t.Run("SharesErrors", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+		fetchErr := errors.New("database is down")
+
+		var calls atomic.Int32
+		var wg sync.WaitGroup
+
+		errs := make([]error, 5)
+
+		for i := range errs {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				_, errs[i] = client.RememberInt(ctx, "stampede-error", time.Minute*5, func(ctx context.Context) (int, error) {
+					calls.Add(1)
+					time.Sleep(time.Millisecond * 100)
+
+					return 0, fetchErr
+				})
+			}(i)
+		}
+
+		wg.Wait()
+
+		assert.Equal(t, int32(1), calls.Load(), "should only call the fetcher once")
+
+		for _, err := range errs {
+			assert.ErrorIs(t, err, fetchErr, "every caller should receive the fetcher error")
+		}
+	})
+
+	t.Run("CancelledWaiterDoesNotCancelOthers", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+		started := make(chan struct{})
+
+		fetcher := func(ctx context.Context) (string, error) {
+			close(started)
+
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(time.Millisecond * 100):
+				return "hello-world", nil
+			}
+		}
+
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancelled := make(chan error, 1)
+
+		go func() {
+			_, err := client.RememberString(cancelCtx, "stampede-cancel", time.Minute*5, fetcher)
+			cancelled <- err
+		}()
+
+		<-started
+
+		var value string
+		var err error
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			value, err = client.RememberString(ctx, "stampede-cancel", time.Minute*5, func(ctx context.Context) (string, error) {
+				return "should not be called", nil
+			})
+		}()
+
+		// give the second caller time to join the in flight fetch before cancelling the first
+		time.Sleep(time.Millisecond * 20)
+		cancel()
+
+		assert.ErrorIs(t, <-cancelled, context.Canceled, "the cancelled caller should stop waiting")
+
+		<-done
+
+		assert.NoError(t, err)
+		assert.Equal(t, "hello-world", value, "the other caller should receive the fetched value")
+	})
+
+	t.Run("LockAcrossClients", func(t *testing.T) {
+		// two clients on one store act like two processes sharing redis
+		store := cacher.NewMemoryStore()
+		clients := []*cacher.Client{cacher.NewWithStore(store), cacher.NewWithStore(store)}
+
+		var calls atomic.Int32
+		var wg sync.WaitGroup
+
+		results := make([]string, 10)
+
+		for i := range results {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				results[i], _ = clients[i%2].RememberString(ctx, "locked", time.Minute*5, func(ctx context.Context) (string, error) {
+					calls.Add(1)
+					time.Sleep(time.Millisecond * 100)
+
+					return "hello-world", nil
+				}, cacher.WithLock(time.Second*5, time.Second*2))
+			}(i)
+		}
+
+		wg.Wait()
+
+		assert.Equal(t, int32(1), calls.Load(), "should only call the fetcher once across both clients")
+
+		for _, result := range results {
+			assert.Equal(t, "hello-world", result, "every caller should receive the fetched value")
+		}
+	})
+
+	t.Run("LockWaitFallsBackToFetcher", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		client := cacher.NewWithStore(store)
+
+		// another process holds the lock and never fills the cache
+		_, _ = store.SetNX(ctx, "cacher:lock:locked-timeout", []byte("someone-else"), time.Minute)
+
+		start := time.Now()
+
+		value, err := client.RememberString(ctx, "locked-timeout", time.Minute*5, func(ctx context.Context) (string, error) {
+			return "hello-world", nil
+		}, cacher.WithLock(time.Minute, time.Millisecond*100))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", value, "should call the fetcher once the wait runs out")
+		assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*100, "should wait for the lock first")
+	})
+
+	t.Run("LockWithoutWait", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		client := cacher.NewWithStore(store)
+
+		var locked bool
+
+		value, err := client.RememberString(ctx, "locked-no-wait", time.Minute*5, func(ctx context.Context) (string, error) {
+			locked, _ = store.Exists(ctx, "cacher:lock:locked-no-wait")
+			return "hello-world", nil
+		}, cacher.WithLock(time.Minute, 0))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", value)
+		assert.True(t, locked, "should hold the lock while fetching even without a wait")
+
+		// without a wait a process that loses the race calls the fetcher straight away
+		_, _ = store.SetNX(ctx, "cacher:lock:locked-no-wait-held", []byte("someone-else"), time.Minute)
+
+		value, _ = client.RememberString(ctx, "locked-no-wait-held", time.Minute*5, func(ctx context.Context) (string, error) {
+			return "hello-world", nil
+		}, cacher.WithLock(time.Minute, 0))
+
+		assert.Equal(t, "hello-world", value)
+	})
+
+	t.Run("LockReleasedWithoutValueIsTakenOver", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		client := cacher.NewWithStore(store)
+
+		_, _ = store.SetNX(ctx, "cacher:lock:locked-takeover", []byte("someone-else"), time.Minute)
+
+		// the other process fails and releases its lock without a value
+		go func() {
+			time.Sleep(time.Millisecond * 50)
+			_, _ = store.CompareAndDelete(ctx, "cacher:lock:locked-takeover", []byte("someone-else"))
+		}()
+
+		start := time.Now()
+
+		value, err := client.RememberString(ctx, "locked-takeover", time.Minute*5, func(ctx context.Context) (string, error) {
+			return "hello-world", nil
+		}, cacher.WithLock(time.Minute, time.Second*5))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", value, "should take over the lock and call the fetcher")
+		assert.Less(t, time.Since(start), time.Second*5, "should not wait for the full timeout")
+
+		exists, _ := store.Exists(ctx, "cacher:lock:locked-takeover")
+		assert.False(t, exists, "should release the lock once the value is cached")
+	})
+	t.Run("CancelsFetchWhenNoCallerWaits", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+		started := make(chan struct{})
+		cancelled := make(chan error, 1)
+
+		cancelCtx, cancel := context.WithCancel(ctx)
+
+		go func() {
+			_, _ = client.RememberString(cancelCtx, "stampede-abandon", time.Minute*5, func(ctx context.Context) (string, error) {
+				close(started)
+				<-ctx.Done()
+				cancelled <- ctx.Err()
+
+				return "", ctx.Err()
+			})
+		}()
+
+		<-started
+		cancel()
+
+		select {
+		case err := <-cancelled:
+			assert.ErrorIs(t, err, context.Canceled, "the fetch should be cancelled once nobody waits for it")
+		case <-time.After(time.Second):
+			t.Error("the fetch was not cancelled")
+		}
+	})
+
+	t.Run("KeepsLatestDeadline", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+		started := make(chan struct{})
+
+		shortCtx, cancelShort := context.WithTimeout(ctx, time.Millisecond*50)
+		defer cancelShort()
+
+		longCtx, cancelLong := context.WithTimeout(ctx, time.Second*5)
+		defer cancelLong()
+
+		fetcher := func(ctx context.Context) (string, error) {
+			close(started)
+
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(time.Millisecond * 200):
+			}
+
+			deadline, ok := ctx.Deadline()
+
+			if !ok || time.Until(deadline) < time.Second {
+				return "", errors.New("should carry the latest deadline")
+			}
+
+			return "hello-world", nil
+		}
+
+		short := make(chan error, 1)
+
+		go func() {
+			_, err := client.RememberString(shortCtx, "stampede-deadline", time.Minute*5, fetcher)
+			short <- err
+		}()
+
+		<-started
+
+		value, err := client.RememberString(longCtx, "stampede-deadline", time.Minute*5, fetcher)
+
+		assert.ErrorIs(t, <-short, context.DeadlineExceeded, "the short caller should stop at its own deadline")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello-world", value, "the fetch should run until the latest deadline")
+	})
+
+	t.Run("FetchHasDeadline", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*50)
+		defer cancel()
+
+		fetched := make(chan error, 1)
+
+		_, err := client.RememberString(timeoutCtx, "stampede-timeout", time.Minute*5, func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			fetched <- ctx.Err()
+
+			return "", ctx.Err()
+		})
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorIs(t, <-fetched, context.DeadlineExceeded, "the fetch should stop at the caller deadline")
+	})
+	t.Run("XFetchRecomputesEarly", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+
+		var calls atomic.Int32
+
+		fetcher := func(ctx context.Context) (string, error) {
+			calls.Add(1)
+			time.Sleep(time.Millisecond * 20)
+
+			return "hello-world", nil
+		}
+
+		// a huge beta makes recomputing long before the expiration all but certain
+		value, err := client.RememberString(ctx, "xfetch", time.Minute, fetcher, cacher.WithXFetch(1000000))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", value)
+
+		value, err = client.RememberString(ctx, "xfetch", time.Minute, fetcher, cacher.WithXFetch(1000000))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", value)
+		assert.Equal(t, int32(2), calls.Load(), "should recompute the value before it expires")
+
+		// without the option the metadata is ignored and the value is served until it expires
+		value, err = client.RememberString(ctx, "xfetch", time.Minute, fetcher)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", value)
+		assert.Equal(t, int32(2), calls.Load(), "should serve the cached value")
+
+		plain, err := client.GetString(ctx, "xfetch")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", plain, "should read the value without the stored metadata")
+	})
+	t.Run("StaleWhileRevalidate", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+
+		var calls atomic.Int32
+
+		fetcher := func(ctx context.Context) (int, error) {
+			return int(calls.Add(1)), nil
+		}
+
+		value, err := client.RememberInt(ctx, "swr", time.Millisecond*50, fetcher, cacher.WithStaleWhileRevalidate(time.Minute))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, 1, value)
+
+		time.Sleep(time.Millisecond * 100)
+
+		value, err = client.RememberInt(ctx, "swr", time.Millisecond*50, fetcher, cacher.WithStaleWhileRevalidate(time.Minute))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, 1, value, "should serve the stale value straight away")
+
+		assert.Eventually(t, func() bool {
+			value, err := client.GetInt(ctx, "swr")
+			return err == nil && value == 2
+		}, time.Second*2, time.Millisecond*10, "should refresh the value in the background")
+	})
+
+	t.Run("StaleWhileRevalidateReportsErrors", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+		fetchErr := errors.New("database is down")
+		reported := make(chan error, 1)
+
+		opts := []cacher.RememberOption{
+			cacher.WithStaleWhileRevalidate(time.Minute),
+			cacher.WithRefreshErrorHandler(func(key string, err error) {
+				reported <- err
+			}),
+		}
+
+		_, _ = client.RememberString(ctx, "swr-error", time.Millisecond*50, func(ctx context.Context) (string, error) {
+			return "hello-world", nil
+		}, opts...)
+
+		time.Sleep(time.Millisecond * 100)
+
+		value, err := client.RememberString(ctx, "swr-error", time.Millisecond*50, func(ctx context.Context) (string, error) {
+			return "", fetchErr
+		}, opts...)
+
+		assert.NoError(t, err, "the caller should not see the refresh error")
+		assert.Equal(t, "hello-world", value, "should serve the stale value")
+
+		select {
+		case err := <-reported:
+			assert.ErrorIs(t, err, fetchErr, "should report the refresh error to the handler")
+		case <-time.After(time.Second):
+			t.Error("the refresh error was not reported")
+		}
+	})
+	t.Run("StaleIfError", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		client := cacher.NewWithStore(store)
+		fetchErr := errors.New("database is down")
+
+		_, _ = client.RememberString(ctx, "stale-if-error", time.Millisecond*50, func(ctx context.Context) (string, error) {
+			return "hello-world", nil
+		}, cacher.WithStaleIfError(time.Minute))
+
+		var keys []string
+
+		_ = store.Scan(ctx, "*", 0, func(page []string) error {
+			keys = append(keys, page...)
+			return nil
+		})
+
+		assert.Equal(t, []string{"stale-if-error"}, keys, "should keep the shadow copy in the key itself")
+
+		time.Sleep(time.Millisecond * 100)
+
+		_, err := client.GetString(ctx, "stale-if-error")
+		assert.ErrorIs(t, err, cacher.NotFoundError, "should not read an expired value kept as a shadow copy")
+
+		value, err := client.RememberString(ctx, "stale-if-error", time.Millisecond*50, func(ctx context.Context) (string, error) {
+			return "", fetchErr
+		}, cacher.WithStaleIfError(time.Minute))
+
+		assert.ErrorIs(t, err, cacher.StaleValueError, "should mark the value as stale")
+		assert.ErrorIs(t, err, fetchErr, "should include the fetcher error")
+		assert.Equal(t, "hello-world", value, "should serve the expired value")
+
+		// forgetting the key removes the shadow copy too
+		_ = client.Forget(ctx, "stale-if-error")
+
+		_, err = client.RememberString(ctx, "stale-if-error", time.Millisecond*50, func(ctx context.Context) (string, error) {
+			return "", fetchErr
+		}, cacher.WithStaleIfError(time.Minute))
+
+		assert.ErrorIs(t, err, fetchErr)
+		assert.NotErrorIs(t, err, cacher.StaleValueError, "should not serve a forgotten value")
+	})
+
+	t.Run("StaleIfErrorWindow", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+		fetchErr := errors.New("database is down")
+
+		_, _ = client.RememberString(ctx, "stale-window", time.Millisecond*50, func(ctx context.Context) (string, error) {
+			return "hello-world", nil
+		}, cacher.WithStaleIfError(time.Millisecond*50))
+
+		time.Sleep(time.Millisecond * 150)
+
+		_, err := client.RememberString(ctx, "stale-window", time.Millisecond*50, func(ctx context.Context) (string, error) {
+			return "", fetchErr
+		}, cacher.WithStaleIfError(time.Millisecond*50))
+
+		assert.ErrorIs(t, err, fetchErr)
+		assert.NotErrorIs(t, err, cacher.StaleValueError, "should not serve a value older than the window")
+	})
+	t.Run("NegativeTTL", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+
+		var calls atomic.Int32
+
+		fetcher := func(ctx context.Context) (string, error) {
+			calls.Add(1)
+			return "", fmt.Errorf("user 1: %w", cacher.NotFoundError)
+		}
+
+		for i := 0; i < 3; i++ {
+			_, err := client.RememberString(ctx, "negative", time.Minute*5, fetcher, cacher.WithNegativeTTL(time.Millisecond*100))
+			assert.ErrorIs(t, err, cacher.NotFoundError, "should report the value as not found")
+		}
+
+		assert.Equal(t, int32(1), calls.Load(), "should remember that there is nothing to fetch")
+
+		_, err := client.GetString(ctx, "negative")
+		assert.ErrorIs(t, err, cacher.NotFoundError, "should read the tombstone as not found")
+
+		time.Sleep(time.Millisecond * 150)
+
+		value, err := client.RememberString(ctx, "negative", time.Minute*5, func(ctx context.Context) (string, error) {
+			return "hello-world", nil
+		}, cacher.WithNegativeTTL(time.Millisecond*100))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", value, "should call the fetcher once the tombstone expires")
+	})
+	t.Run("CachesZeroValues", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+
+		var calls atomic.Int32
+
+		for i := 0; i < 3; i++ {
+			count, err := client.RememberInt(ctx, "zero-int", time.Minute*5, func(ctx context.Context) (int, error) {
+				calls.Add(1)
+				return 0, nil
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, 0, count)
+
+			name, err := client.RememberString(ctx, "zero-string", time.Minute*5, func(ctx context.Context) (string, error) {
+				calls.Add(1)
+				return "", nil
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, "", name)
+
+			data, err := client.RememberBytes(ctx, "zero-bytes", time.Minute*5, func(ctx context.Context) ([]byte, error) {
+				calls.Add(1)
+				return nil, nil
+			})
+
+			assert.NoError(t, err)
+			assert.Empty(t, data)
+
+			ratio, err := client.RememberFloat64(ctx, "zero-float", time.Minute*5, func(ctx context.Context) (float64, error) {
+				calls.Add(1)
+				return 0, nil
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, float64(0), ratio)
+		}
+
+		assert.Equal(t, int32(4), calls.Load(), "should only call each fetcher once")
+	})
+}
@@ -0,0 +1,64 @@
+package cacher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// schemaMagic marks entities stored with a schema version by WithSchemaVersion. It is followed by the uvarint encoded
+// version and the marshalled entity.
+const schemaMagic = "\x00cacher\x02"
+
+// stamp prefixes the marshalled entity with the schema version of the client. Entities are stored as is when no
+// version is set so they stay readable by clients that predate versioning.
+func (c *EntityClient[E]) stamp(data []byte) []byte {
+	if c.version == 0 {
+		return data
+	}
+
+	stamped := make([]byte, 0, len(schemaMagic)+binary.MaxVarintLen64+len(data))
+	stamped = append(stamped, schemaMagic...)
+	stamped = binary.AppendUvarint(stamped, uint64(c.version))
+
+	return append(stamped, data...)
+}
+
+// upgrade strips the schema version from a stored entity and migrates it to the version of the client.
+func (c *EntityClient[E]) upgrade(data []byte) ([]byte, error) {
+	version := 0
+
+	if bytes.HasPrefix(data, []byte(schemaMagic)) {
+		v, n := binary.Uvarint(data[len(schemaMagic):])
+
+		if n <= 0 {
+			return nil, errors.Join(SchemaVersionError, errors.New("invalid schema version"))
+		}
+
+		version = int(v)
+		data = data[len(schemaMagic)+n:]
+	}
+
+	for ; version < c.version; version++ {
+		migrate, ok := c.migrations[version]
+
+		if !ok {
+			break
+		}
+
+		migrated, err := migrate(data)
+
+		if err != nil {
+			return nil, errors.Join(SchemaVersionError, fmt.Errorf("migrating from version %d: %w", version, err))
+		}
+
+		data = migrated
+	}
+
+	if version != c.version {
+		return nil, errors.Join(SchemaVersionError, fmt.Errorf("stored as version %d, expected %d", version, c.version))
+	}
+
+	return data, nil
+}
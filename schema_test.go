@@ -0,0 +1,172 @@
+package cacher_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPerson is the second version of a schema whose first version stored the name in a single field.
+type TestPerson struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// splitName migrates a person from a single name field to a first and last name.
+func splitName(data []byte) ([]byte, error) {
+	var old struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.Unmarshal(data, &old); err != nil {
+		return nil, err
+	}
+
+	person := TestPerson{FirstName: old.Name}
+
+	for i, r := range old.Name {
+		if r == ' ' {
+			person.FirstName, person.LastName = old.Name[:i], old.Name[i+1:]
+			break
+		}
+	}
+
+	return json.Marshal(person)
+}
+
+func TestSchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		client := cacher.NewEntityWithStore[TestPerson](cacher.NewMemoryStore(), cacher.WithSchemaVersion(2))
+
+		person := &TestPerson{FirstName: "Ada", LastName: "Lovelace"}
+
+		err := client.Put(ctx, "person", person, time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		value, err := client.Get(ctx, "person")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, person, value)
+	})
+
+	t.Run("MismatchIsAnError", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+
+		_ = cacher.NewWithStore(store).Put(ctx, "person", `{"name":"Ada Lovelace"}`, time.Minute*5)
+
+		client := cacher.NewEntityWithStore[TestPerson](store, cacher.WithSchemaVersion(1))
+
+		_, err := client.Get(ctx, "person")
+		assert.ErrorIs(t, err, cacher.SchemaVersionError, "should not read values stored without a version")
+
+		_ = cacher.NewEntityWithStore[TestPerson](store, cacher.WithSchemaVersion(2)).Put(ctx, "person", &TestPerson{}, time.Minute*5)
+
+		_, err = client.Get(ctx, "person")
+		assert.ErrorIs(t, err, cacher.SchemaVersionError, "should not read values stored by a newer version")
+
+		_, err = cacher.NewEntityWithStore[TestPerson](store).Get(ctx, "person")
+		assert.ErrorIs(t, err, cacher.SchemaVersionError, "should not read versioned values without a version")
+	})
+
+	t.Run("MismatchIsAMiss", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+
+		_ = cacher.NewWithStore(store).Put(ctx, "person", `{"name":"Ada Lovelace"}`, time.Minute*5)
+
+		client := cacher.NewEntityWithStore[TestPerson](store, cacher.WithSchemaVersion(1))
+
+		person := &TestPerson{FirstName: "Ada", LastName: "Lovelace"}
+
+		value, err := client.Remember(ctx, "person", time.Minute*5, func(ctx context.Context) (*TestPerson, error) {
+			return person, nil
+		}, cacher.WithXFetch(1))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, person, value, "should call the fetcher")
+
+		value, err = client.Get(ctx, "person")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, person, value, "should store the fetched value under the current version")
+	})
+
+	t.Run("Migration", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+
+		_ = cacher.NewWithStore(store).Put(ctx, "person", `{"name":"Ada Lovelace"}`, time.Minute*5)
+		_ = cacher.NewWithStore(store).Put(ctx, "people", `[{"name":"Ada Lovelace"}]`, time.Minute*5)
+
+		client := cacher.NewEntityWithStore[TestPerson](store,
+			cacher.WithSchemaVersion(2),
+			cacher.WithMigration(0, func(data []byte) ([]byte, error) {
+				return data, nil
+			}),
+			cacher.WithMigration(1, func(data []byte) ([]byte, error) {
+				// lists are migrated one entity at a time
+				if data[0] == '[' {
+					var people []json.RawMessage
+
+					if err := json.Unmarshal(data, &people); err != nil {
+						return nil, err
+					}
+
+					for i := range people {
+						migrated, err := splitName(people[i])
+
+						if err != nil {
+							return nil, err
+						}
+
+						people[i] = migrated
+					}
+
+					return json.Marshal(people)
+				}
+
+				return splitName(data)
+			}),
+		)
+
+		value, err := client.Get(ctx, "person")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, &TestPerson{FirstName: "Ada", LastName: "Lovelace"}, value, "should migrate through every version")
+
+		values, err := client.GetMany(ctx, "people")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, []*TestPerson{{FirstName: "Ada", LastName: "Lovelace"}}, values, "should migrate lists")
+	})
+}
@@ -0,0 +1,46 @@
+package cacher
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Run runs a Lua script on the redis connection of the client, so extensions such as the ratelimit package can update
+// keys atomically. Keys are resolved like every other key of the client, within its namespace and tags, and must all
+// hash to the same cluster slot. It returns a ScriptNotSupportedError if the client is not backed by a RedisStore or
+// a TieredStore.
+func (c *Client) Run(ctx context.Context, script *redis.Script, keys []string, args ...any) (any, error) {
+	scripter := scripterOf(c.store)
+
+	if scripter == nil {
+		return nil, ScriptNotSupportedError
+	}
+
+	resolved := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		key, err := c.key(ctx, key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resolved = append(resolved, key)
+	}
+
+	return script.Run(ctx, scripter, resolved, args...).Result()
+}
+
+// scripterOf returns the redis connection scripts run on for the store, or nil if it is not backed by redis.
+func scripterOf(s Store) redis.Scripter {
+	switch s := s.(type) {
+	case *RedisStore:
+		return s.redis
+	case *TieredStore:
+		// the local copies of keys changed by a script are not invalidated, scripts should only touch their own keys
+		return s.remote.redis
+	default:
+		return nil
+	}
+}
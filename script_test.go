@@ -0,0 +1,73 @@
+package cacher_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arhea/go-cacher"
+	mockredis "github.com/arhea/go-mock-redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	mock, err := mockredis.NewClient(ctx, t)
+
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	r := mock.Client()
+
+	script := redis.NewScript(`return redis.call("INCRBY", KEYS[1], ARGV[1])`)
+
+	t.Run("RedisStore", func(t *testing.T) {
+		client := cacher.New(r).WithNamespace("scripts")
+
+		reply, err := client.Run(ctx, script, []string{"counter"}, 5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, int64(5), reply)
+
+		value, _ := r.Get(ctx, "scripts:counter").Int64()
+		assert.Equal(t, int64(5), value, "should resolve the keys within the namespace")
+	})
+
+	t.Run("TieredStore", func(t *testing.T) {
+		store, err := cacher.NewTieredStore(ctx, r)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		t.Cleanup(func() {
+			_ = store.Close()
+		})
+
+		reply, err := cacher.NewWithStore(store).Run(ctx, script, []string{"tiered-counter"}, 2)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, int64(2), reply, "should run on the remote tier")
+	})
+
+	t.Run("MemoryStore", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+
+		_, err := client.Run(ctx, script, []string{"counter"}, 1)
+		assert.ErrorIs(t, err, cacher.ScriptNotSupportedError)
+	})
+}
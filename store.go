@@ -0,0 +1,51 @@
+package cacher
+
+import (
+	"context"
+	"time"
+)
+
+// Store is the backend the Client reads and writes raw values through. Implementations must return a NotFoundError
+// from Get when the key does not exist. An expiration of 0 means the key never expires.
+type Store interface {
+	// Get returns the raw value stored under the key or a NotFoundError if it does not exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set stores the raw value under the key with the given expiration. An expiration of redis.KeepTTL keeps the
+	// expiration of an existing key.
+	Set(ctx context.Context, key string, value []byte, exp time.Duration) error
+
+	// Del removes the keys and returns how many of them existed.
+	Del(ctx context.Context, keys ...string) (int64, error)
+
+	// Exists checks if the key exists.
+	Exists(ctx context.Context, key string) (bool, error)
+
+	// IncrBy increments the integer stored under the key by value and returns the result. A missing key is treated
+	// as 0.
+	IncrBy(ctx context.Context, key string, value int64) (int64, error)
+
+	// Scan walks every key matching the glob style pattern and calls fn with each batch of keys found. The count is
+	// a hint for how many keys to examine per batch, 0 lets the store decide.
+	Scan(ctx context.Context, match string, count int64, fn func(keys []string) error) error
+
+	// Expire updates the expiration of an existing key. It returns false if the key does not exist. A negative
+	// expiration deletes the key.
+	Expire(ctx context.Context, key string, exp time.Duration) (bool, error)
+
+	// SetNX stores the raw value under the key only if the key does not exist. It returns true if the value was set.
+	SetNX(ctx context.Context, key string, value []byte, exp time.Duration) (bool, error)
+
+	// SetNXAndIncr stores the raw value under the key only if the key does not exist and increments the integer stored
+	// under counter in the same atomic step. It returns true and the result of the increment if the value was set.
+	// Both keys must hash to the same cluster slot.
+	SetNXAndIncr(ctx context.Context, key string, value []byte, exp time.Duration, counter string) (bool, int64, error)
+
+	// CompareAndDelete atomically removes the key only if it holds the given value. It returns true if the key was
+	// removed.
+	CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error)
+
+	// CompareAndExpire atomically updates the expiration of the key only if it holds the given value. It returns true
+	// if the expiration was updated.
+	CompareAndExpire(ctx context.Context, key string, value []byte, exp time.Duration) (bool, error)
+}
@@ -0,0 +1,514 @@
+package cacher
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryValueTooLargeError is returned when a single value is larger than the maximum size of a MemoryStore.
+var MemoryValueTooLargeError = errors.New("value exceeds the memory store size limit")
+
+// NotIntegerError is returned when incrementing a value that is not an integer, mirroring the redis error.
+var NotIntegerError = errors.New("value is not an integer or out of range")
+
+// Eviction selects which entry a MemoryStore removes when it is full.
+type Eviction int
+
+const (
+	// EvictLRU removes the least recently used entry.
+	EvictLRU Eviction = iota
+
+	// EvictLFU removes the least frequently used entry, breaking ties by the least recently used.
+	EvictLFU
+)
+
+// MemoryOption configures a MemoryStore.
+type MemoryOption func(s *MemoryStore)
+
+// WithMaxEntries limits the number of keys held by the MemoryStore. A limit of 0 means unlimited.
+func WithMaxEntries(n int) MemoryOption {
+	return func(s *MemoryStore) {
+		s.maxEntries = n
+	}
+}
+
+// WithMaxBytes limits the combined size of the keys and values held by the MemoryStore. A limit of 0 means unlimited.
+func WithMaxBytes(n int64) MemoryOption {
+	return func(s *MemoryStore) {
+		s.maxBytes = n
+	}
+}
+
+// WithEviction sets the policy used to make room when the MemoryStore is full. The default is EvictLRU.
+func WithEviction(e Eviction) MemoryOption {
+	return func(s *MemoryStore) {
+		s.eviction = e
+	}
+}
+
+// NewMemoryStore creates a Store that keeps values in the memory of the current process.
+func NewMemoryStore(opts ...MemoryOption) *MemoryStore {
+	s := &MemoryStore{
+		entries: make(map[string]*memoryEntry),
+		now:     time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.eviction == EvictLFU {
+		s.policy = newLFUPolicy()
+	} else {
+		s.policy = newLRUPolicy()
+	}
+
+	return s
+}
+
+// MemoryStore is a Store that keeps values in process. Expired keys are removed lazily when they are accessed and
+// the store evicts entries using the configured policy once it reaches its limits.
+type MemoryStore struct {
+	mu         sync.Mutex
+	entries    map[string]*memoryEntry
+	policy     evictionPolicy
+	size       int64
+	maxEntries int
+	maxBytes   int64
+	eviction   Eviction
+	now        func() time.Time
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+	freq      int
+	element   *list.Element
+}
+
+func (e *memoryEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// Get returns the raw value stored under the key or a NotFoundError if it does not exist.
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.lookup(key)
+
+	if entry == nil {
+		return nil, NotFoundError
+	}
+
+	s.policy.touch(entry)
+
+	return append([]byte{}, entry.value...), nil
+}
+
+// Set stores the raw value under the key with the given expiration. Like redis an expiration of redis.KeepTTL keeps
+// the expiration of an existing key and any other expiration of 0 or less stores the key without one.
+func (s *MemoryStore) Set(ctx context.Context, key string, value []byte, exp time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt := s.expiresAt(exp)
+
+	if exp == redis.KeepTTL {
+		if entry := s.lookup(key); entry != nil {
+			expiresAt = entry.expiresAt
+		}
+	}
+
+	return s.set(key, append([]byte{}, value...), expiresAt)
+}
+
+// Del removes the keys and returns how many of them existed.
+func (s *MemoryStore) Del(ctx context.Context, keys ...string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed int64
+
+	for _, key := range keys {
+		if entry := s.lookup(key); entry != nil {
+			s.remove(entry)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// Exists checks if the key exists.
+func (s *MemoryStore) Exists(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lookup(key) != nil, nil
+}
+
+// IncrBy increments the integer stored under the key by value and returns the result. The expiration of an existing
+// key is kept. It returns a NotIntegerError if the stored value is not an integer.
+func (s *MemoryStore) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.incrBy(key, value)
+}
+
+// incrBy increments the integer stored under the key while holding the store lock.
+func (s *MemoryStore) incrBy(key string, value int64) (int64, error) {
+	var current int64
+	var expiresAt time.Time
+
+	if entry := s.lookup(key); entry != nil {
+		parsed, err := strconv.ParseInt(string(entry.value), 10, 64)
+
+		if err != nil {
+			return 0, NotIntegerError
+		}
+
+		current = parsed
+		expiresAt = entry.expiresAt
+	}
+
+	if (value > 0 && current > math.MaxInt64-value) || (value < 0 && current < math.MinInt64-value) {
+		return 0, NotIntegerError
+	}
+
+	current += value
+
+	if err := s.set(key, strconv.AppendInt(nil, current, 10), expiresAt); err != nil {
+		return 0, err
+	}
+
+	return current, nil
+}
+
+// Scan walks every key matching the glob style pattern and calls fn with batches of at most count keys. The batches
+// are collected up front so fn may modify the store.
+func (s *MemoryStore) Scan(ctx context.Context, match string, count int64, fn func(keys []string) error) error {
+	if count <= 0 {
+		count = 10
+	}
+
+	s.mu.Lock()
+
+	now := s.now()
+	keys := make([]string, 0)
+
+	for key, entry := range s.entries {
+		if entry.expired(now) {
+			s.remove(entry)
+			continue
+		}
+
+		if matchGlob(match, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	s.mu.Unlock()
+
+	for start := 0; start < len(keys); start += int(count) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := min(start+int(count), len(keys))
+
+		if err := fn(keys[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Expire updates the expiration of an existing key. An expiration of 0 removes the expiration from the key and a
+// negative expiration deletes the key like redis does.
+func (s *MemoryStore) Expire(ctx context.Context, key string, exp time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.lookup(key)
+
+	if entry == nil {
+		return false, nil
+	}
+
+	if exp < 0 {
+		s.remove(entry)
+		return true, nil
+	}
+
+	entry.expiresAt = s.expiresAt(exp)
+
+	return true, nil
+}
+
+// SetNX stores the raw value under the key only if the key does not exist. It returns true if the value was set.
+func (s *MemoryStore) SetNX(ctx context.Context, key string, value []byte, exp time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lookup(key) != nil {
+		return false, nil
+	}
+
+	if err := s.set(key, append([]byte{}, value...), s.expiresAt(exp)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SetNXAndIncr stores the raw value under the key only if the key does not exist and increments the counter in the
+// same step.
+func (s *MemoryStore) SetNXAndIncr(ctx context.Context, key string, value []byte, exp time.Duration, counter string) (bool, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lookup(key) != nil {
+		return false, 0, nil
+	}
+
+	result, err := s.incrBy(counter, 1)
+
+	if err != nil {
+		return false, 0, err
+	}
+
+	if err := s.set(key, append([]byte{}, value...), s.expiresAt(exp)); err != nil {
+		return false, 0, err
+	}
+
+	return true, result, nil
+}
+
+// CompareAndDelete atomically removes the key only if it holds the given value.
+func (s *MemoryStore) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.lookup(key)
+
+	if entry == nil || !bytes.Equal(entry.value, value) {
+		return false, nil
+	}
+
+	s.remove(entry)
+
+	return true, nil
+}
+
+// CompareAndExpire atomically updates the expiration of the key only if it holds the given value.
+func (s *MemoryStore) CompareAndExpire(ctx context.Context, key string, value []byte, exp time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.lookup(key)
+
+	if entry == nil || !bytes.Equal(entry.value, value) {
+		return false, nil
+	}
+
+	entry.expiresAt = s.expiresAt(exp)
+
+	return true, nil
+}
+
+// Len returns the number of keys held by the store including keys that have expired but not yet been removed.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries)
+}
+
+// lookup returns the live entry for the key, removing it if it has expired.
+func (s *MemoryStore) lookup(key string) *memoryEntry {
+	entry, ok := s.entries[key]
+
+	if !ok {
+		return nil
+	}
+
+	if entry.expired(s.now()) {
+		s.remove(entry)
+		return nil
+	}
+
+	return entry
+}
+
+// set replaces the entry for the key and evicts entries until the store is within its limits.
+func (s *MemoryStore) set(key string, value []byte, expiresAt time.Time) error {
+	entry := &memoryEntry{
+		key:       key,
+		value:     value,
+		expiresAt: expiresAt,
+	}
+
+	if s.maxBytes > 0 && entry.size() > s.maxBytes {
+		return MemoryValueTooLargeError
+	}
+
+	if existing, ok := s.entries[key]; ok {
+		s.remove(existing)
+	}
+
+	// make room before adding so a new entry is never chosen as its own victim
+	for len(s.entries) > 0 && s.full(entry.size()) {
+		s.remove(s.policy.victim())
+	}
+
+	s.entries[key] = entry
+	s.size += entry.size()
+	s.policy.add(entry)
+
+	return nil
+}
+
+func (s *MemoryStore) remove(entry *memoryEntry) {
+	delete(s.entries, entry.key)
+	s.size -= entry.size()
+	s.policy.remove(entry)
+}
+
+// full reports whether adding an entry of the given size would take the store over its limits.
+func (s *MemoryStore) full(size int64) bool {
+	if s.maxEntries > 0 && len(s.entries)+1 > s.maxEntries {
+		return true
+	}
+
+	return s.maxBytes > 0 && s.size+size > s.maxBytes
+}
+
+func (s *MemoryStore) expiresAt(exp time.Duration) time.Time {
+	if exp <= 0 {
+		return time.Time{}
+	}
+
+	return s.now().Add(exp)
+}
+
+// evictionPolicy tracks the order entries should be evicted in. It is only used while holding the store lock.
+type evictionPolicy interface {
+	add(entry *memoryEntry)
+	touch(entry *memoryEntry)
+	remove(entry *memoryEntry)
+	victim() *memoryEntry
+}
+
+// lruPolicy keeps entries in a list ordered from most to least recently used.
+type lruPolicy struct {
+	order *list.List
+}
+
+func newLRUPolicy() *lruPolicy {
+	return &lruPolicy{
+		order: list.New(),
+	}
+}
+
+func (p *lruPolicy) add(entry *memoryEntry) {
+	entry.element = p.order.PushFront(entry)
+}
+
+func (p *lruPolicy) touch(entry *memoryEntry) {
+	p.order.MoveToFront(entry.element)
+}
+
+func (p *lruPolicy) remove(entry *memoryEntry) {
+	p.order.Remove(entry.element)
+}
+
+func (p *lruPolicy) victim() *memoryEntry {
+	if back := p.order.Back(); back != nil {
+		return back.Value.(*memoryEntry)
+	}
+
+	return nil
+}
+
+// lfuPolicy keeps a list of entries per access frequency so the least frequently used entry can be found in
+// constant time. Entries with the same frequency are ordered from most to least recently used.
+type lfuPolicy struct {
+	buckets map[int]*list.List
+	min     int
+}
+
+func newLFUPolicy() *lfuPolicy {
+	return &lfuPolicy{
+		buckets: make(map[int]*list.List),
+	}
+}
+
+func (p *lfuPolicy) add(entry *memoryEntry) {
+	entry.freq = 1
+	p.push(entry)
+	p.min = 1
+}
+
+func (p *lfuPolicy) touch(entry *memoryEntry) {
+	p.remove(entry)
+
+	entry.freq++
+	p.push(entry)
+}
+
+func (p *lfuPolicy) remove(entry *memoryEntry) {
+	bucket := p.buckets[entry.freq]
+	bucket.Remove(entry.element)
+
+	if bucket.Len() == 0 {
+		delete(p.buckets, entry.freq)
+
+		if p.min == entry.freq {
+			p.min++
+		}
+	}
+}
+
+func (p *lfuPolicy) victim() *memoryEntry {
+	if len(p.buckets) == 0 {
+		return nil
+	}
+
+	// the minimum may be stale after removals so find the lowest populated frequency
+	if _, ok := p.buckets[p.min]; !ok {
+		p.min = math.MaxInt
+
+		for freq := range p.buckets {
+			p.min = min(p.min, freq)
+		}
+	}
+
+	return p.buckets[p.min].Back().Value.(*memoryEntry)
+}
+
+func (p *lfuPolicy) push(entry *memoryEntry) {
+	bucket, ok := p.buckets[entry.freq]
+
+	if !ok {
+		bucket = list.New()
+		p.buckets[entry.freq] = bucket
+	}
+
+	entry.element = bucket.PushFront(entry)
+}
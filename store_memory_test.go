@@ -0,0 +1,360 @@
+package cacher_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("Client", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+
+		err := client.Put(ctx, "memory-basic", 92, time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		value, err := client.GetInt(ctx, "memory-basic")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, 92, value, "should be equal as the value was stored")
+
+		_, err = client.GetString(ctx, "memory-missing")
+		assert.ErrorIs(t, err, cacher.NotFoundError)
+
+		remembered, err := client.RememberString(ctx, "memory-remember", time.Minute*5, func(ctx context.Context) (string, error) {
+			return "hello-world", nil
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", remembered, "value should be equal to the fetched value")
+
+		remembered, err = client.RememberString(ctx, "memory-remember", time.Minute*5, func(ctx context.Context) (string, error) {
+			return "some other value", nil
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", remembered, "value should still be equal to the fetched value as it should be in the cache")
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+
+		err := store.Set(ctx, "memory-ttl", []byte("hello-world"), time.Millisecond*50)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		time.Sleep(time.Millisecond * 100)
+
+		_, err = store.Get(ctx, "memory-ttl")
+		assert.ErrorIs(t, err, cacher.NotFoundError, "should be expired and therefore not exist")
+	})
+
+	t.Run("ForgetWithPrefix", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+
+		_ = client.Put(ctx, "prefix-1", "something", time.Minute*5)
+		_ = client.Put(ctx, "prefix-2", "something", time.Minute*5)
+		_ = client.Put(ctx, "other-1", "something", time.Minute*5)
+
+		removed, err := client.ForgetWithPrefix(ctx, "prefix-")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, int64(2), removed, "should return how many keys were removed")
+
+		has1, _ := client.Has(ctx, "prefix-1")
+		has2, _ := client.Has(ctx, "prefix-2")
+		has3, _ := client.Has(ctx, "other-1")
+
+		assert.False(t, has1, "should be false as the key has been forgotten")
+		assert.False(t, has2, "should be false as the key has been forgotten")
+		assert.True(t, has3, "should be true as the key does not match the prefix")
+	})
+
+	t.Run("ScanPatterns", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+
+		for _, key := range []string{"hello", "hallo", "hxllo", "heeeello", "hllo", "h*llo"} {
+			_ = store.Set(ctx, key, []byte("1"), 0)
+		}
+
+		scan := func(match string) []string {
+			found := make([]string, 0)
+
+			_ = store.Scan(ctx, match, 2, func(keys []string) error {
+				found = append(found, keys...)
+				return nil
+			})
+
+			sort.Strings(found)
+
+			return found
+		}
+
+		assert.Equal(t, []string{"h*llo", "hallo", "hello", "hxllo"}, scan("h?llo"))
+		assert.Equal(t, []string{"h*llo", "hallo", "heeeello", "hello", "hllo", "hxllo"}, scan("h*llo"))
+		assert.Equal(t, []string{"hallo", "hello"}, scan("h[ae]llo"))
+		assert.Equal(t, []string{"h*llo", "hxllo"}, scan("h[^e-a]llo"))
+		assert.Equal(t, []string{"h*llo"}, scan("h\\*llo"))
+	})
+
+	t.Run("Increment", func(t *testing.T) {
+		client := cacher.NewWithStore(cacher.NewMemoryStore())
+
+		_ = client.Put(ctx, "counter", 1, time.Minute*5)
+		_, _ = client.Increment(ctx, "counter", 5)
+		_, _ = client.Decrement(ctx, "counter", 2)
+
+		value, err := client.GetInt(ctx, "counter")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, 4, value, "should be equal as the value was incremented and decremented")
+
+		_ = client.Put(ctx, "not-a-counter", "hello-world", time.Minute*5)
+
+		_, err = client.Increment(ctx, "not-a-counter", 1)
+		assert.ErrorIs(t, err, cacher.NotIntegerError)
+	})
+
+	t.Run("EvictLRU", func(t *testing.T) {
+		store := cacher.NewMemoryStore(cacher.WithMaxEntries(2))
+
+		_ = store.Set(ctx, "a", []byte("1"), 0)
+		_ = store.Set(ctx, "b", []byte("2"), 0)
+
+		// touch a so b becomes the least recently used
+		_, _ = store.Get(ctx, "a")
+
+		_ = store.Set(ctx, "c", []byte("3"), 0)
+
+		hasA, _ := store.Exists(ctx, "a")
+		hasB, _ := store.Exists(ctx, "b")
+		hasC, _ := store.Exists(ctx, "c")
+
+		assert.True(t, hasA, "should be kept as it was recently used")
+		assert.False(t, hasB, "should be evicted as it was least recently used")
+		assert.True(t, hasC, "should be kept as it was just added")
+		assert.Equal(t, 2, store.Len())
+	})
+
+	t.Run("EvictLFU", func(t *testing.T) {
+		store := cacher.NewMemoryStore(cacher.WithMaxEntries(2), cacher.WithEviction(cacher.EvictLFU))
+
+		_ = store.Set(ctx, "a", []byte("1"), 0)
+		_ = store.Set(ctx, "b", []byte("2"), 0)
+
+		// a is used more often, b is used most recently
+		_, _ = store.Get(ctx, "a")
+		_, _ = store.Get(ctx, "a")
+		_, _ = store.Get(ctx, "b")
+
+		_ = store.Set(ctx, "c", []byte("3"), 0)
+
+		hasA, _ := store.Exists(ctx, "a")
+		hasB, _ := store.Exists(ctx, "b")
+		hasC, _ := store.Exists(ctx, "c")
+
+		assert.True(t, hasA, "should be kept as it was used most frequently")
+		assert.False(t, hasB, "should be evicted as it was least frequently used")
+		assert.True(t, hasC, "should be kept as it was just added")
+	})
+
+	t.Run("MaxBytes", func(t *testing.T) {
+		store := cacher.NewMemoryStore(cacher.WithMaxBytes(10))
+
+		_ = store.Set(ctx, "a", []byte("123456"), 0)
+		_ = store.Set(ctx, "b", []byte("123456"), 0)
+
+		hasA, _ := store.Exists(ctx, "a")
+		hasB, _ := store.Exists(ctx, "b")
+
+		assert.False(t, hasA, "should be evicted to make room")
+		assert.True(t, hasB, "should be kept as it was just added")
+
+		err := store.Set(ctx, "c", []byte("12345678910"), 0)
+		assert.ErrorIs(t, err, cacher.MemoryValueTooLargeError)
+	})
+	t.Run("SetNXAndCompareAndDelete", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		key := "memory-setnx"
+
+		ok, err := store.SetNX(ctx, key, []byte("owner-1"), time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, ok, "should be set as the key does not exist")
+
+		ok, err = store.SetNX(ctx, key, []byte("owner-2"), time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.False(t, ok, "should not be set as the key exists")
+
+		removed, err := store.CompareAndDelete(ctx, key, []byte("owner-2"))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.False(t, removed, "should not remove a key holding another value")
+
+		removed, err = store.CompareAndDelete(ctx, key, []byte("owner-1"))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, removed, "should remove the key holding the value")
+
+		exists, _ := store.Exists(ctx, key)
+		assert.False(t, exists, "should be removed")
+	})
+	t.Run("NegativeExpireDeletes", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		key := "memory-negative-expire"
+
+		_ = store.Set(ctx, key, []byte("value"), time.Minute*5)
+
+		ok, err := store.Expire(ctx, key, -time.Second)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, ok, "should be true as the key existed")
+
+		exists, _ := store.Exists(ctx, key)
+		assert.False(t, exists, "should be deleted by a negative expiration")
+	})
+
+	t.Run("SetNXAndIncr", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		key := "memory-setnx-incr"
+
+		ok, counter, err := store.SetNXAndIncr(ctx, key, []byte("owner-1"), time.Minute*5, key+"-counter")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, ok, "should be set as the key does not exist")
+		assert.Equal(t, int64(1), counter, "should increment the counter")
+
+		ok, counter, err = store.SetNXAndIncr(ctx, key, []byte("owner-2"), time.Minute*5, key+"-counter")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.False(t, ok, "should not be set as the key exists")
+		assert.Zero(t, counter)
+
+		_, _ = store.Del(ctx, key)
+
+		ok, counter, _ = store.SetNXAndIncr(ctx, key, []byte("owner-2"), 0, key+"-counter")
+
+		assert.True(t, ok)
+		assert.Equal(t, int64(2), counter, "should keep counting")
+
+		data, _ := store.Get(ctx, key)
+		assert.Equal(t, []byte("owner-2"), data)
+	})
+
+	t.Run("CompareAndExpire", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		key := "memory-compare-expire"
+
+		_ = store.Set(ctx, key, []byte("owner-1"), time.Millisecond*200)
+
+		ok, err := store.CompareAndExpire(ctx, key, []byte("owner-2"), time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.False(t, ok, "should not extend a key holding another value")
+
+		ok, err = store.CompareAndExpire(ctx, key, []byte("owner-1"), time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, ok, "should extend the key holding the value")
+
+		time.Sleep(time.Millisecond * 400)
+
+		exists, _ := store.Exists(ctx, key)
+		assert.True(t, exists, "should have the new expiration")
+	})
+
+	t.Run("KeepTTL", func(t *testing.T) {
+		store := cacher.NewMemoryStore()
+		key := "memory-keep-ttl"
+
+		_ = store.Set(ctx, key, []byte("value"), time.Second)
+
+		err := store.Set(ctx, key, []byte("updated"), redis.KeepTTL)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		data, _ := store.Get(ctx, key)
+		assert.Equal(t, []byte("updated"), data, "should store the new value")
+
+		time.Sleep(time.Second * 2)
+
+		exists, _ := store.Exists(ctx, key)
+		assert.False(t, exists, "should keep the original expiration and therefore be expired")
+	})
+}
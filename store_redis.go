@@ -0,0 +1,225 @@
+package cacher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// compareAndDeleteScript removes a key only if it still holds the expected value.
+var compareAndDeleteScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+
+return 0
+`)
+
+// compareAndExpireScript updates the expiration of a key only if it still holds the expected value.
+var compareAndExpireScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+
+return 0
+`)
+
+// setNXAndIncrScript sets a key only if it does not exist and increments a counter when it does.
+var setNXAndIncrScript = redis.NewScript(`
+local set
+
+if ARGV[2] == "0" then
+	set = redis.call("SET", KEYS[1], ARGV[1], "NX")
+else
+	set = redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2])
+end
+
+if set then
+	return {1, redis.call("INCR", KEYS[2])}
+end
+
+return {0, 0}
+`)
+
+// NewRedisStore creates a Store backed by an existing redis client. Any client topology is supported including
+// single nodes, sentinel failover, cluster and ring clients. This will not close the redis client.
+func NewRedisStore(r redis.UniversalClient) *RedisStore {
+	return &RedisStore{
+		redis: r,
+	}
+}
+
+// RedisStore is a Store that keeps values in redis using the official go-redis client.
+type RedisStore struct {
+	redis redis.UniversalClient
+}
+
+// Get returns the raw value stored under the key or a NotFoundError if it does not exist.
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	cmd := s.redis.Get(ctx, key)
+	err := cmd.Err()
+
+	if errors.Is(err, redis.Nil) {
+		return nil, NotFoundError
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd.Bytes()
+}
+
+// Set stores the raw value under the key with the given expiration.
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, exp time.Duration) error {
+	cmd := s.redis.Set(ctx, key, value, exp)
+	return cmd.Err()
+}
+
+// Del removes the keys and returns how many of them existed. Each key is unlinked with its own command in a single
+// pipeline so keys hashing to different cluster slots can be removed together. UNLINK reclaims the memory of large
+// values in the background instead of blocking redis.
+func (s *RedisStore) Del(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	if len(keys) == 1 {
+		cmd := s.redis.Unlink(ctx, keys[0])
+		return cmd.Val(), cmd.Err()
+	}
+
+	cmds, err := s.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Unlink(ctx, key)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int64
+
+	for _, cmd := range cmds {
+		removed += cmd.(*redis.IntCmd).Val()
+	}
+
+	return removed, nil
+}
+
+// Exists checks if the key exists.
+func (s *RedisStore) Exists(ctx context.Context, key string) (bool, error) {
+	cmd := s.redis.Exists(ctx, key)
+	err := cmd.Err()
+
+	if err != nil {
+		return false, err
+	}
+
+	return cmd.Val() == 1, nil
+}
+
+// IncrBy increments the integer stored under the key by value and returns the result.
+func (s *RedisStore) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
+	cmd := s.redis.IncrBy(ctx, key, value)
+	return cmd.Val(), cmd.Err()
+}
+
+// Scan walks every key matching the pattern using SCAN and calls fn with each page of keys. Cluster and ring clients
+// are scanned on every master or shard as each node only holds part of the keyspace. The nodes are scanned
+// concurrently but fn is never called concurrently.
+func (s *RedisStore) Scan(ctx context.Context, match string, count int64, fn func(keys []string) error) error {
+	var mu sync.Mutex
+
+	scan := func(ctx context.Context, node *redis.Client) error {
+		return scanNode(ctx, node, match, count, func(keys []string) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return fn(keys)
+		})
+	}
+
+	switch r := s.redis.(type) {
+	case *redis.ClusterClient:
+		return r.ForEachMaster(ctx, scan)
+	case *redis.Ring:
+		return r.ForEachShard(ctx, scan)
+	default:
+		return scanNode(ctx, s.redis, match, count, fn)
+	}
+}
+
+// Expire updates the expiration of an existing key. An expiration of 0 removes the expiration from the key.
+func (s *RedisStore) Expire(ctx context.Context, key string, exp time.Duration) (bool, error) {
+	if exp == 0 {
+		// persist reports false for keys without an expiration so fall back to checking existence
+		if ok, err := s.redis.Persist(ctx, key).Result(); err != nil || ok {
+			return ok, err
+		}
+
+		return s.Exists(ctx, key)
+	}
+
+	return s.redis.PExpire(ctx, key, exp).Result()
+}
+
+// SetNX stores the raw value under the key only if the key does not exist. It returns true if the value was set.
+func (s *RedisStore) SetNX(ctx context.Context, key string, value []byte, exp time.Duration) (bool, error) {
+	return s.redis.SetNX(ctx, key, value, exp).Result()
+}
+
+// SetNXAndIncr stores the raw value under the key only if the key does not exist and increments the counter in the
+// same lua script.
+func (s *RedisStore) SetNXAndIncr(ctx context.Context, key string, value []byte, exp time.Duration, counter string) (bool, int64, error) {
+	result, err := setNXAndIncrScript.Run(ctx, s.redis, []string{key, counter}, value, exp.Milliseconds()).Int64Slice()
+
+	if err != nil {
+		return false, 0, err
+	}
+
+	return result[0] == 1, result[1], nil
+}
+
+// CompareAndDelete atomically removes the key only if it holds the given value using a lua script.
+func (s *RedisStore) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	removed, err := compareAndDeleteScript.Run(ctx, s.redis, []string{key}, value).Int64()
+	return removed == 1, err
+}
+
+// CompareAndExpire atomically updates the expiration of the key only if it holds the given value using a lua script.
+func (s *RedisStore) CompareAndExpire(ctx context.Context, key string, value []byte, exp time.Duration) (bool, error) {
+	updated, err := compareAndExpireScript.Run(ctx, s.redis, []string{key}, value, exp.Milliseconds()).Int64()
+	return updated == 1, err
+}
+
+// scanNode walks every key matching the pattern on a single node.
+func scanNode(ctx context.Context, node redis.Cmdable, match string, count int64, fn func(keys []string) error) error {
+	var cursor uint64
+
+	for {
+		keys, next, err := node.Scan(ctx, cursor, match, count).Result()
+
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+
+		cursor = next
+	}
+}
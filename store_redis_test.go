@@ -0,0 +1,394 @@
+package cacher_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	mockredis "github.com/arhea/go-mock-redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	mock, err := mockredis.NewClient(ctx, t)
+
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	store := cacher.NewRedisStore(mock.Client())
+
+	t.Run("GetSetDel", func(t *testing.T) {
+		key := "store-basic"
+
+		_, err := store.Get(ctx, key)
+		assert.ErrorIs(t, err, cacher.NotFoundError)
+
+		err = store.Set(ctx, key, []byte("hello-world"), time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		value, err := store.Get(ctx, key)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, []byte("hello-world"), value, "should be equal as the value was stored")
+
+		removed, err := store.Del(ctx, key, "store-missing")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, int64(1), removed, "should only count the key that existed")
+
+		exists, err := store.Exists(ctx, key)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.False(t, exists, "should be false as the key has been removed")
+	})
+
+	t.Run("IncrBy", func(t *testing.T) {
+		key := "store-counter"
+
+		value, err := store.IncrBy(ctx, key, 5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, int64(5), value, "should start from zero")
+
+		value, err = store.IncrBy(ctx, key, -2)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, int64(3), value, "should be decremented")
+	})
+
+	t.Run("Scan", func(t *testing.T) {
+		_ = store.Set(ctx, "scan-1", []byte("1"), time.Minute*5)
+		_ = store.Set(ctx, "scan-2", []byte("2"), time.Minute*5)
+		_ = store.Set(ctx, "other-1", []byte("3"), time.Minute*5)
+
+		found := make([]string, 0)
+
+		err := store.Scan(ctx, "scan-*", 0, func(keys []string) error {
+			found = append(found, keys...)
+			return nil
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		sort.Strings(found)
+
+		assert.Equal(t, []string{"scan-1", "scan-2"}, found, "should only find the keys matching the pattern")
+	})
+
+	t.Run("Expire", func(t *testing.T) {
+		key := "store-expire"
+
+		ok, err := store.Expire(ctx, key, time.Second)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.False(t, ok, "should be false as the key does not exist")
+
+		_ = store.Set(ctx, key, []byte("value"), 0)
+
+		ok, err = store.Expire(ctx, key, time.Second)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, ok, "should be true as the key exists")
+
+		time.Sleep(time.Second * 2)
+
+		exists, err := store.Exists(ctx, key)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.False(t, exists, "should be expired and therefore not exist")
+	})
+
+	t.Run("Cluster", func(t *testing.T) {
+		other, err := mockredis.NewClient(ctx, t)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		nodes := []*redis.Client{mock.Client(), other.Client()}
+
+		// split the slots across two standalone servers so the cluster has two masters
+		cluster := redis.NewClusterClient(&redis.ClusterOptions{
+			ClusterSlots: func(ctx context.Context) ([]redis.ClusterSlot, error) {
+				return []redis.ClusterSlot{
+					{Start: 0, End: 8191, Nodes: []redis.ClusterNode{{Addr: nodes[0].Options().Addr}}},
+					{Start: 8192, End: 16383, Nodes: []redis.ClusterNode{{Addr: nodes[1].Options().Addr}}},
+				}, nil
+			},
+		})
+
+		t.Cleanup(func() {
+			_ = cluster.Close()
+		})
+
+		// write to each node directly so every master is known to hold keys
+		for i, node := range nodes {
+			for j := 0; j < 3; j++ {
+				if err := node.Set(ctx, fmt.Sprintf("cluster-%d-%d", i, j), "value", time.Minute*5).Err(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}
+
+		keys := make([]string, 0)
+
+		err = cacher.NewRedisStore(cluster).Scan(ctx, "cluster-*", 0, func(batch []string) error {
+			keys = append(keys, batch...)
+			return nil
+		})
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		sort.Strings(keys)
+
+		assert.Equal(t, []string{"cluster-0-0", "cluster-0-1", "cluster-0-2", "cluster-1-0", "cluster-1-1", "cluster-1-2"}, keys, "should scan every master")
+	})
+
+	t.Run("Ring", func(t *testing.T) {
+		ring := redis.NewRing(&redis.RingOptions{
+			Addrs: map[string]string{
+				"shard1": mock.Client().Options().Addr,
+			},
+		})
+
+		t.Cleanup(func() {
+			_ = ring.Close()
+		})
+
+		client := cacher.New(ring)
+
+		_ = client.Put(ctx, "ring-1", "something", time.Minute*5)
+		_ = client.Put(ctx, "ring-2", "something", time.Minute*5)
+
+		removed, err := client.ForgetWithPrefix(ctx, "ring-")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, int64(2), removed, "should count the keys removed from every shard")
+
+		has1, _ := client.Has(ctx, "ring-1")
+		has2, _ := client.Has(ctx, "ring-2")
+
+		assert.False(t, has1, "should be false as the key has been forgotten on the shard")
+		assert.False(t, has2, "should be false as the key has been forgotten on the shard")
+	})
+	t.Run("SetNXAndCompareAndDelete", func(t *testing.T) {
+		key := "redis-setnx"
+
+		ok, err := store.SetNX(ctx, key, []byte("owner-1"), time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, ok, "should be set as the key does not exist")
+
+		ok, err = store.SetNX(ctx, key, []byte("owner-2"), time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.False(t, ok, "should not be set as the key exists")
+
+		removed, err := store.CompareAndDelete(ctx, key, []byte("owner-2"))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.False(t, removed, "should not remove a key holding another value")
+
+		removed, err = store.CompareAndDelete(ctx, key, []byte("owner-1"))
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, removed, "should remove the key holding the value")
+
+		exists, _ := store.Exists(ctx, key)
+		assert.False(t, exists, "should be removed")
+	})
+	t.Run("ExpireMilliseconds", func(t *testing.T) {
+		key := "store-expire-ms"
+
+		_ = store.Set(ctx, key, []byte("value"), 0)
+
+		ok, err := store.Expire(ctx, key, time.Millisecond*250)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, ok)
+
+		ttl, _ := mock.Client().PTTL(ctx, key).Result()
+		assert.Positive(t, ttl)
+		assert.LessOrEqual(t, ttl, time.Millisecond*250, "should keep millisecond precision like MemoryStore")
+	})
+
+	t.Run("NegativeExpireDeletes", func(t *testing.T) {
+		key := "store-negative-expire"
+
+		_ = store.Set(ctx, key, []byte("value"), time.Minute*5)
+
+		ok, err := store.Expire(ctx, key, -time.Second)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, ok, "should be true as the key existed")
+
+		exists, _ := store.Exists(ctx, key)
+		assert.False(t, exists, "should be deleted by a negative expiration")
+	})
+
+	t.Run("SetNXAndIncr", func(t *testing.T) {
+		key := "redis-setnx-incr"
+
+		ok, counter, err := store.SetNXAndIncr(ctx, key, []byte("owner-1"), time.Minute*5, key+"-counter")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, ok, "should be set as the key does not exist")
+		assert.Equal(t, int64(1), counter, "should increment the counter")
+
+		ok, counter, err = store.SetNXAndIncr(ctx, key, []byte("owner-2"), time.Minute*5, key+"-counter")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.False(t, ok, "should not be set as the key exists")
+		assert.Zero(t, counter)
+
+		_, _ = store.Del(ctx, key)
+
+		ok, counter, _ = store.SetNXAndIncr(ctx, key, []byte("owner-2"), 0, key+"-counter")
+
+		assert.True(t, ok)
+		assert.Equal(t, int64(2), counter, "should keep counting")
+
+		data, _ := store.Get(ctx, key)
+		assert.Equal(t, []byte("owner-2"), data)
+	})
+
+	t.Run("CompareAndExpire", func(t *testing.T) {
+		key := "redis-compare-expire"
+
+		_ = store.Set(ctx, key, []byte("owner-1"), time.Second)
+
+		ok, err := store.CompareAndExpire(ctx, key, []byte("owner-2"), time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.False(t, ok, "should not extend a key holding another value")
+
+		ok, err = store.CompareAndExpire(ctx, key, []byte("owner-1"), time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, ok, "should extend the key holding the value")
+
+		time.Sleep(time.Second * 2)
+
+		exists, _ := store.Exists(ctx, key)
+		assert.True(t, exists, "should have the new expiration")
+	})
+
+	t.Run("KeepTTL", func(t *testing.T) {
+		key := "store-keep-ttl"
+
+		_ = store.Set(ctx, key, []byte("value"), time.Second)
+
+		err := store.Set(ctx, key, []byte("updated"), redis.KeepTTL)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		data, _ := store.Get(ctx, key)
+		assert.Equal(t, []byte("updated"), data, "should store the new value")
+
+		time.Sleep(time.Second * 2)
+
+		exists, _ := store.Exists(ctx, key)
+		assert.False(t, exists, "should keep the original expiration and therefore be expired")
+	})
+}
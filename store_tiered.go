@@ -0,0 +1,306 @@
+package cacher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/maphash"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TieredOption configures a TieredStore.
+type TieredOption func(s *TieredStore)
+
+// WithLocalStore sets the in process store used as the first tier. The default is a MemoryStore holding at most
+// 10,000 entries.
+func WithLocalStore(local *MemoryStore) TieredOption {
+	return func(s *TieredStore) {
+		s.local = local
+	}
+}
+
+// WithLocalTTL sets the maximum time a value is kept in the local tier. This bounds how stale a value can become if
+// an invalidation message is lost. The default is one minute.
+func WithLocalTTL(ttl time.Duration) TieredOption {
+	return func(s *TieredStore) {
+		s.localTTL = ttl
+	}
+}
+
+// WithInvalidationChannel sets the redis pub/sub channel used to broadcast invalidations between processes. Every
+// TieredStore sharing a cache must use the same channel. The default is "cacher:invalidate".
+func WithInvalidationChannel(channel string) TieredOption {
+	return func(s *TieredStore) {
+		s.channel = channel
+	}
+}
+
+// NewTieredStore creates a Store that reads through a bounded in process cache before falling back to redis. Writes
+// and deletes go to redis and are broadcast over pub/sub so every TieredStore drops its local copy. It returns an
+// error if the subscription could not be established. Close must be called to stop listening for invalidations, it
+// will not close the redis client.
+func NewTieredStore(ctx context.Context, r redis.UniversalClient, opts ...TieredOption) (*TieredStore, error) {
+	id, err := newToken()
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := &TieredStore{
+		seed:     maphash.MakeSeed(),
+		remote:   NewRedisStore(r),
+		redis:    r,
+		id:       id,
+		localTTL: time.Minute,
+		channel:  "cacher:invalidate",
+		done:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.local == nil {
+		s.local = NewMemoryStore(WithMaxEntries(10000))
+	}
+
+	s.pubsub = r.Subscribe(ctx, s.channel)
+
+	// wait for the subscription to be confirmed so no invalidations are missed after returning
+	if _, err := s.pubsub.Receive(ctx); err != nil {
+		return nil, errors.Join(s.pubsub.Close(), err)
+	}
+
+	go s.listen()
+
+	return s, nil
+}
+
+// tieredEpochs is the number of invalidation counters keys are spread across. Keys sharing a counter only cause
+// an occasional skipped local copy.
+const tieredEpochs = 256
+
+// TieredStore is a Store with a local first tier and redis as the second tier. See NewTieredStore.
+type TieredStore struct {
+	mu       sync.Mutex
+	epochs   [tieredEpochs]uint64
+	seed     maphash.Seed
+	local    *MemoryStore
+	remote   *RedisStore
+	redis    redis.UniversalClient
+	pubsub   *redis.PubSub
+	id       string
+	localTTL time.Duration
+	channel  string
+	done     chan struct{}
+}
+
+// invalidation is the message broadcast to other processes when keys change.
+type invalidation struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys"`
+}
+
+// Get returns the value from the local tier if present, otherwise it reads it from redis and keeps a local copy for
+// no longer than the remaining redis expiration. The copy is not kept if the key was invalidated while reading it as
+// the value read may already be stale.
+func (s *TieredStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if data, err := s.local.Get(ctx, key); err == nil {
+		return data, nil
+	}
+
+	epoch := s.epoch(key)
+
+	pipe := s.redis.Pipeline()
+	get := pipe.Get(ctx, key)
+	ttl := pipe.PTTL(ctx, key)
+
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	if errors.Is(get.Err(), redis.Nil) {
+		return nil, NotFoundError
+	}
+
+	data, err := get.Bytes()
+
+	if err != nil {
+		return nil, err
+	}
+
+	exp := s.localTTL
+
+	if remaining := ttl.Val(); remaining > 0 && remaining < exp {
+		exp = remaining
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the local tier is best effort so a failure to populate it is not an error
+	if s.epochs[s.slot(key)] == epoch {
+		_ = s.local.Set(ctx, key, data, exp)
+	}
+
+	return data, nil
+}
+
+// Set stores the value in redis and tells every process to drop its local copy. The local tier is only populated by
+// reads so it never holds a value that a concurrent write in another process has already replaced.
+func (s *TieredStore) Set(ctx context.Context, key string, value []byte, exp time.Duration) error {
+	if err := s.remote.Set(ctx, key, value, exp); err != nil {
+		return err
+	}
+
+	s.invalidate(key)
+
+	return s.publish(ctx, key)
+}
+
+// Del removes the keys from both tiers and tells every other process to drop its local copy.
+func (s *TieredStore) Del(ctx context.Context, keys ...string) (int64, error) {
+	removed, err := s.remote.Del(ctx, keys...)
+
+	if err != nil {
+		return 0, err
+	}
+
+	s.invalidate(keys...)
+
+	return removed, s.publish(ctx, keys...)
+}
+
+// Exists checks the local tier before checking redis.
+func (s *TieredStore) Exists(ctx context.Context, key string) (bool, error) {
+	if exists, _ := s.local.Exists(ctx, key); exists {
+		return true, nil
+	}
+
+	return s.remote.Exists(ctx, key)
+}
+
+// IncrBy increments the value in redis and invalidates every local copy.
+func (s *TieredStore) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
+	result, err := s.remote.IncrBy(ctx, key, value)
+
+	if err != nil {
+		return 0, err
+	}
+
+	s.invalidate(key)
+
+	return result, s.publish(ctx, key)
+}
+
+// Scan walks the keys in redis, the local tier only ever holds a subset of them.
+func (s *TieredStore) Scan(ctx context.Context, match string, count int64, fn func(keys []string) error) error {
+	return s.remote.Scan(ctx, match, count, fn)
+}
+
+// Expire updates the expiration in redis and invalidates every local copy.
+func (s *TieredStore) Expire(ctx context.Context, key string, exp time.Duration) (bool, error) {
+	ok, err := s.remote.Expire(ctx, key, exp)
+
+	if err != nil {
+		return false, err
+	}
+
+	s.invalidate(key)
+
+	return ok, s.publish(ctx, key)
+}
+
+// SetNX stores the value in redis only if the key does not exist there. No process can hold a local copy of a key
+// that does not exist in redis so there is nothing to invalidate.
+func (s *TieredStore) SetNX(ctx context.Context, key string, value []byte, exp time.Duration) (bool, error) {
+	return s.remote.SetNX(ctx, key, value, exp)
+}
+
+// SetNXAndIncr stores the value in redis only if the key does not exist there and increments the counter. Neither key
+// can be held in the local tier, conditional keys are never read into it and the counter only changes in redis.
+func (s *TieredStore) SetNXAndIncr(ctx context.Context, key string, value []byte, exp time.Duration, counter string) (bool, int64, error) {
+	return s.remote.SetNXAndIncr(ctx, key, value, exp, counter)
+}
+
+// CompareAndDelete atomically removes the key from redis only if it holds the given value. Conditional keys such as
+// locks are never read into the local tier so there is nothing to invalidate.
+func (s *TieredStore) CompareAndDelete(ctx context.Context, key string, value []byte) (bool, error) {
+	return s.remote.CompareAndDelete(ctx, key, value)
+}
+
+// CompareAndExpire atomically updates the expiration of the key in redis only if it holds the given value.
+// Conditional keys are never read into the local tier so there is nothing to invalidate.
+func (s *TieredStore) CompareAndExpire(ctx context.Context, key string, value []byte, exp time.Duration) (bool, error) {
+	return s.remote.CompareAndExpire(ctx, key, value, exp)
+}
+
+// Close stops listening for invalidations. The store must not be used after it has been closed as the local tier
+// would no longer be kept coherent.
+func (s *TieredStore) Close() error {
+	err := s.pubsub.Close()
+	<-s.done
+
+	return err
+}
+
+// publish broadcasts that the keys have changed.
+func (s *TieredStore) publish(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	msg, err := json.Marshal(invalidation{
+		Origin: s.id,
+		Keys:   keys,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return s.redis.Publish(ctx, s.channel, msg).Err()
+}
+
+// listen drops local copies of keys changed by other processes until the subscription is closed.
+func (s *TieredStore) listen() {
+	defer close(s.done)
+
+	for msg := range s.pubsub.Channel() {
+		var inv invalidation
+
+		if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil || inv.Origin == s.id {
+			continue
+		}
+
+		s.invalidate(inv.Keys...)
+	}
+}
+
+// invalidate drops the local copies of the keys and advances their epochs so reads already in flight do not put
+// them back.
+func (s *TieredStore) invalidate(keys ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		s.epochs[s.slot(key)]++
+	}
+
+	_, _ = s.local.Del(context.Background(), keys...)
+}
+
+// epoch returns the current invalidation epoch of the key.
+func (s *TieredStore) epoch(key string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.epochs[s.slot(key)]
+}
+
+func (s *TieredStore) slot(key string) uint64 {
+	return maphash.String(s.seed, key) % tieredEpochs
+}
@@ -0,0 +1,301 @@
+package cacher_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	mockredis "github.com/arhea/go-mock-redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTieredStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	mock, err := mockredis.NewClient(ctx, t)
+
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	r := mock.Client()
+
+	// two stores sharing the same redis act like two processes
+	store1, err := cacher.NewTieredStore(ctx, r)
+
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	t.Cleanup(func() {
+		_ = store1.Close()
+	})
+
+	store2, err := cacher.NewTieredStore(ctx, r)
+
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	t.Cleanup(func() {
+		_ = store2.Close()
+	})
+
+	client1 := cacher.NewWithStore(store1)
+	client2 := cacher.NewWithStore(store2)
+
+	t.Run("ReadsFromLocalTier", func(t *testing.T) {
+		key := "tiered-local"
+
+		// write behind the back of the stores so no invalidation races the read
+		err := r.Set(ctx, key, "hello-world", time.Minute*5).Err()
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		value, err := client2.GetString(ctx, key)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", value, "should be read through from redis")
+
+		// remove the key behind the back of the stores so only the local tier has it
+		if err := r.Del(ctx, key).Err(); err != nil {
+			t.Error(err)
+			return
+		}
+
+		value, err = client2.GetString(ctx, key)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", value, "should be served from the local tier")
+	})
+
+	t.Run("PutInvalidatesOtherStores", func(t *testing.T) {
+		key := "tiered-put"
+
+		_ = client1.Put(ctx, key, "value-1", time.Minute*5)
+
+		value, err := client2.GetString(ctx, key)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "value-1", value)
+
+		err = client1.Put(ctx, key, "value-2", time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Eventually(t, func() bool {
+			value, err := client2.GetString(ctx, key)
+			return err == nil && value == "value-2"
+		}, time.Second*2, time.Millisecond*10, "should drop the stale local copy")
+	})
+
+	t.Run("ForgetInvalidatesOtherStores", func(t *testing.T) {
+		_ = client1.Put(ctx, "tiered-prefix-1", "value", time.Minute*5)
+		_ = client1.Put(ctx, "tiered-prefix-2", "value", time.Minute*5)
+
+		_, _ = client2.GetString(ctx, "tiered-prefix-1")
+		_, _ = client2.GetString(ctx, "tiered-prefix-2")
+
+		_, err := client1.ForgetWithPrefix(ctx, "tiered-prefix-")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Eventually(t, func() bool {
+			has1, _ := client2.Has(ctx, "tiered-prefix-1")
+			has2, _ := client2.Has(ctx, "tiered-prefix-2")
+			return !has1 && !has2
+		}, time.Second*2, time.Millisecond*10, "should drop the forgotten local copies")
+	})
+
+	t.Run("IncrementInvalidatesOtherStores", func(t *testing.T) {
+		key := "tiered-incr"
+
+		_ = client1.Put(ctx, key, 1, time.Minute*5)
+
+		value, err := client2.GetInt(ctx, key)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, 1, value)
+
+		_, err = client1.Increment(ctx, key, 1)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Eventually(t, func() bool {
+			value, err := client2.GetInt(ctx, key)
+			return err == nil && value == 2
+		}, time.Second*2, time.Millisecond*10, "should drop the stale local copy")
+	})
+
+	t.Run("ExpireInvalidatesOtherStores", func(t *testing.T) {
+		key := "tiered-expire"
+
+		_ = store1.Set(ctx, key, []byte("value"), time.Minute*5)
+		_, _ = store2.Get(ctx, key)
+
+		ok, err := store1.Expire(ctx, key, -time.Second)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.True(t, ok, "should be true as the key existed")
+
+		assert.Eventually(t, func() bool {
+			_, err := store2.Get(ctx, key)
+			return errors.Is(err, cacher.NotFoundError)
+		}, time.Second*2, time.Millisecond*10, "should drop the expired local copy")
+	})
+
+	t.Run("LocalTTLCappedByRedis", func(t *testing.T) {
+		key := "tiered-ttl"
+
+		_ = store1.Set(ctx, key, []byte("value"), time.Millisecond*200)
+
+		data, err := store2.Get(ctx, key)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, []byte("value"), data)
+
+		time.Sleep(time.Millisecond * 400)
+
+		_, err = store2.Get(ctx, key)
+		assert.ErrorIs(t, err, cacher.NotFoundError, "the local copy should expire with the redis key")
+	})
+
+	t.Run("ConcurrentWriteAndInvalidate", func(t *testing.T) {
+		key := "tiered-race"
+		marker := "tiered-race-marker"
+
+		// pause reads of the key on a third store after redis has answered but before the local tier is populated
+		hook := &pauseHook{key: key, read: make(chan struct{}), resume: make(chan struct{})}
+
+		paused := redis.NewClient(&redis.Options{Addr: r.Options().Addr})
+		paused.AddHook(hook)
+
+		t.Cleanup(func() {
+			_ = paused.Close()
+		})
+
+		store3, err := cacher.NewTieredStore(ctx, paused)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		t.Cleanup(func() {
+			_ = store3.Close()
+		})
+
+		_ = store1.Set(ctx, key, []byte("value-1"), time.Minute*5)
+
+		// hold a local copy of the marker so we can tell when later invalidations have been delivered
+		_ = r.Set(ctx, marker, "marker-1", time.Minute*5).Err()
+		_, _ = store3.Get(ctx, marker)
+
+		done := make(chan []byte)
+
+		go func() {
+			data, _ := store3.Get(ctx, key)
+			done <- data
+		}()
+
+		<-hook.read
+
+		// replace the value while the read is in flight and wait for the invalidation to arrive
+		_ = store1.Set(ctx, key, []byte("value-2"), time.Minute*5)
+		_ = store1.Set(ctx, marker, []byte("marker-2"), time.Minute*5)
+
+		assert.Eventually(t, func() bool {
+			data, err := store3.Get(ctx, marker)
+			return err == nil && string(data) == "marker-2"
+		}, time.Second*2, time.Millisecond*10, "should receive the invalidations")
+
+		close(hook.resume)
+
+		assert.Equal(t, "value-1", string(<-done), "the racing read returns the value it read")
+
+		data, err := store3.Get(ctx, key)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "value-2", string(data), "the racing read should not keep a stale local copy")
+	})
+}
+
+// pauseHook blocks the first pipeline reading key after it has executed until resume is closed.
+type pauseHook struct {
+	key    string
+	read   chan struct{}
+	resume chan struct{}
+	once   sync.Once
+}
+
+func (h *pauseHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *pauseHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+func (h *pauseHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+
+		if len(cmds) > 0 && len(cmds[0].Args()) > 1 && cmds[0].Args()[1] == h.key {
+			h.once.Do(func() {
+				close(h.read)
+				<-h.resume
+			})
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,126 @@
+package cacher
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// tagPrefix is the reserved namespace holding the current id of every tag.
+const tagPrefix = "cacher:tag:"
+
+// taggedPrefix is the reserved namespace the values written under tags live in.
+const taggedPrefix = "cacher:tagged:"
+
+// Tags returns a view of the client that reads and writes keys under the given tags. Every key written through the view
+// is removed from the cache when FlushTags is called with any of its tags. Keys must be read with the same tags they
+// were written with, the view does not see keys written without them or under other tags.
+//
+// Each tag is identified by a random id stored in the cache and keys are stored under a hash of the ids of their tags.
+// Flushing a tag replaces its id so the keys written under the old one are never read again and expire with their
+// own expiration. Keys stored forever are never removed, give tagged keys an expiration.
+func (c *Client) Tags(names ...string) *Client {
+	view := *c
+	view.tags = append(slices.Clone(c.tags), names...)
+
+	// the order tags are given in does not matter
+	slices.Sort(view.tags)
+	view.tags = slices.Compact(view.tags)
+
+	return &view
+}
+
+// FlushTags removes every key written under any of the given tags. It returns an error if there was one.
+func (c *Client) FlushTags(ctx context.Context, names ...string) error {
+	prefix, err := c.prefix(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		id, err := newToken()
+
+		if err != nil {
+			return err
+		}
+
+		if err := c.store.Set(ctx, prefix+tagPrefix+name, []byte(id), 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// key returns the key the value of the given key is stored under by this client, within its namespace and tags.
+func (c *Client) key(ctx context.Context, key string) (string, error) {
+	prefix, err := c.prefix(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(c.tags) == 0 {
+		return prefix + key, nil
+	}
+
+	ids := make([]string, 0, len(c.tags))
+
+	for _, name := range c.tags {
+		id, err := c.tagID(ctx, prefix, name)
+
+		if err != nil {
+			return "", err
+		}
+
+		ids = append(ids, name+"="+id)
+	}
+
+	sum := sha1.Sum([]byte(strings.Join(ids, "|")))
+
+	return prefix + taggedPrefix + hex.EncodeToString(sum[:]) + ":" + key, nil
+}
+
+// tagID returns the current id of the tag within the key prefix, creating it if the tag has never been used or was evicted.
+func (c *Client) tagID(ctx context.Context, prefix string, name string) (string, error) {
+	tagKey := prefix + tagPrefix + name
+
+	data, err := c.store.Get(ctx, tagKey)
+
+	if err == nil {
+		return string(data), nil
+	}
+
+	if !errors.Is(err, NotFoundError) {
+		return "", err
+	}
+
+	id, err := newToken()
+
+	if err != nil {
+		return "", err
+	}
+
+	created, err := c.store.SetNX(ctx, tagKey, []byte(id), 0)
+
+	if err != nil {
+		return "", err
+	}
+
+	if created {
+		return id, nil
+	}
+
+	// another process created the tag at the same time, use its id
+	data, err = c.store.Get(ctx, tagKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
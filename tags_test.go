@@ -0,0 +1,154 @@
+package cacher_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	mockredis "github.com/arhea/go-mock-redis"
+	"github.com/stretchr/testify/assert"
+)
+
+// noScanStore fails every scan to prove an operation does not walk the keyspace.
+type noScanStore struct {
+	*cacher.MemoryStore
+}
+
+func (s noScanStore) Scan(ctx context.Context, match string, count int64, fn func(keys []string) error) error {
+	return errors.New("scan is not allowed")
+}
+
+func TestTags(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	mock, err := mockredis.NewClient(ctx, t)
+
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+
+	client := cacher.New(mock.Client())
+
+	t.Run("PutAndGet", func(t *testing.T) {
+		err := client.Tags("user:1", "org:1").Put(ctx, "profile", "hello-world", time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		value, err := client.Tags("org:1", "user:1").GetString(ctx, "profile")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "hello-world", value, "should read the key whatever the order of the tags")
+
+		_, err = client.GetString(ctx, "profile")
+		assert.ErrorIs(t, err, cacher.NotFoundError, "should not see tagged keys without the tags")
+
+		_, err = client.Tags("user:1").GetString(ctx, "profile")
+		assert.ErrorIs(t, err, cacher.NotFoundError, "should not see tagged keys with other tags")
+	})
+
+	t.Run("FlushTags", func(t *testing.T) {
+		_ = client.Tags("user:2", "org:2").Put(ctx, "profile", "user-and-org", time.Minute*5)
+		_ = client.Tags("user:2").Put(ctx, "settings", "user-only", time.Minute*5)
+		_ = client.Tags("user:3").Put(ctx, "settings", "other-user", time.Minute*5)
+
+		err := client.FlushTags(ctx, "user:2")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		_, err = client.Tags("user:2", "org:2").GetString(ctx, "profile")
+		assert.ErrorIs(t, err, cacher.NotFoundError, "should flush keys with any of the tags")
+
+		has, err := client.Tags("user:2").Has(ctx, "settings")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.False(t, has, "should flush keys with only the flushed tag")
+
+		value, err := client.Tags("user:3").GetString(ctx, "settings")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, "other-user", value, "should keep keys with other tags")
+	})
+
+	t.Run("Remember", func(t *testing.T) {
+		calls := 0
+
+		fetcher := func(ctx context.Context) (int, error) {
+			calls++
+			return calls, nil
+		}
+
+		tagged := client.Tags("user:4")
+
+		value, _ := tagged.RememberInt(ctx, "visits", time.Minute*5, fetcher)
+		assert.Equal(t, 1, value)
+
+		value, _ = tagged.RememberInt(ctx, "visits", time.Minute*5, fetcher)
+		assert.Equal(t, 1, value, "should be cached under the tag")
+
+		_ = client.FlushTags(ctx, "user:4")
+
+		value, _ = tagged.RememberInt(ctx, "visits", time.Minute*5, fetcher)
+		assert.Equal(t, 2, value, "should call the fetcher once the tag is flushed")
+	})
+
+	t.Run("Entity", func(t *testing.T) {
+		entities := cacher.NewEntityWithClient[TestEntity](client).Tags("org:5")
+
+		entity := &TestEntity{ID: "entity-id", Name: "entity-name"}
+
+		_ = entities.Put(ctx, "entity", entity, time.Minute*5)
+
+		value, err := entities.Get(ctx, "entity")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		assert.Equal(t, entity, value)
+
+		_ = entities.FlushTags(ctx, "org:5")
+
+		_, err = entities.Get(ctx, "entity")
+		assert.ErrorIs(t, err, cacher.NotFoundError, "should flush tagged entities")
+	})
+
+	t.Run("FlushWithoutScan", func(t *testing.T) {
+		client := cacher.NewWithStore(noScanStore{cacher.NewMemoryStore()})
+
+		_ = client.Tags("user:6").Put(ctx, "profile", "value", time.Minute*5)
+
+		err := client.FlushTags(ctx, "user:6")
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		has, _ := client.Tags("user:6").Has(ctx, "profile")
+		assert.False(t, has, "should flush the tag without scanning")
+	})
+}
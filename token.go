@@ -0,0 +1,17 @@
+package cacher
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// newToken returns a random hex encoded token used to identify the owner of a lock or a process.
+func newToken() (string, error) {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,48 @@
+package cacher
+
+import (
+	"context"
+	"time"
+)
+
+// Get retrieves the value of the key as a T. Strings, byte slices, booleans, numbers, time.Time, time.Duration and
+// net.IP are read as written by Client.Put. Other types are decoded with encoding.BinaryUnmarshaler or
+// encoding.TextUnmarshaler when they implement them and from JSON otherwise. If the key does not exist it will return
+// a NotFoundError.
+func Get[T any](ctx context.Context, c *Client, key string) (T, error) {
+	var zero T
+
+	data, err := c.get(ctx, key)
+
+	if err != nil {
+		return zero, err
+	}
+
+	return codecFor[T]().decode(data)
+}
+
+// Put adds a value of type T to the cache with an expiration, encoding it the same way Get decodes it. It returns an
+// error if there was one.
+func Put[T any](ctx context.Context, c *Client, key string, value T, exp time.Duration) error {
+	data, err := codecFor[T]().encode(value)
+
+	if err != nil {
+		return err
+	}
+
+	key, err = c.key(ctx, key)
+
+	if err != nil {
+		return err
+	}
+
+	return c.write(ctx, key, data, exp)
+}
+
+// Remember will attempt to get the value of type T from the cache. If it is not found it will call the fetcher
+// function to get the value. It will then put the value in the cache for later. It returns the value or an error if
+// there was one.
+func Remember[T any](ctx context.Context, c *Client, key string, exp time.Duration, fetcher func(ctx context.Context) (T, error), opts ...RememberOption) (T, error) {
+	codec := codecFor[T]()
+	return remember(ctx, c, key, exp, codec.decode, codec.encode, fetcher, opts)
+}
@@ -0,0 +1,140 @@
+package cacher_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arhea/go-cacher"
+	"github.com/stretchr/testify/assert"
+)
+
+// point is stored as text through encoding.TextMarshaler.
+type point struct {
+	X, Y string
+}
+
+func (p point) MarshalText() ([]byte, error) {
+	return []byte(p.X + "," + p.Y), nil
+}
+
+func (p *point) UnmarshalText(data []byte) error {
+	x, y, ok := strings.Cut(string(data), ",")
+
+	if !ok {
+		return errors.New("invalid point")
+	}
+
+	p.X, p.Y = x, y
+
+	return nil
+}
+
+// label only knows how to read itself from text so it is stored as json.
+type label string
+
+func (l *label) UnmarshalText(data []byte) error {
+	*l = label(data)
+	return nil
+}
+
+func TestTyped(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := cacher.NewWithStore(cacher.NewMemoryStore())
+
+	t.Run("Scalars", func(t *testing.T) {
+		now := time.Now().UTC()
+
+		_ = cacher.Put(ctx, client, "typed-time", now, time.Minute*5)
+		_ = cacher.Put(ctx, client, "typed-duration", time.Second*90, time.Minute*5)
+		_ = cacher.Put(ctx, client, "typed-uint8", uint8(200), time.Minute*5)
+		_ = cacher.Put(ctx, client, "typed-ip", net.ParseIP("10.0.0.1"), time.Minute*5)
+
+		storedTime, err := cacher.Get[time.Time](ctx, client, "typed-time")
+		assert.NoError(t, err)
+		assert.True(t, now.Equal(storedTime), "should read back the time")
+
+		storedDuration, err := cacher.Get[time.Duration](ctx, client, "typed-duration")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Second*90, storedDuration)
+
+		storedUint8, err := cacher.Get[uint8](ctx, client, "typed-uint8")
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(200), storedUint8)
+
+		storedIP, err := cacher.Get[net.IP](ctx, client, "typed-ip")
+		assert.NoError(t, err)
+		assert.True(t, net.ParseIP("10.0.0.1").Equal(storedIP), "should read back the ip")
+
+		// values are stored the same way as Client.Put so the untyped functions can read them
+		raw, err := client.GetString(ctx, "typed-duration")
+		assert.NoError(t, err)
+		assert.Equal(t, "90000000000", raw)
+	})
+
+	t.Run("TextMarshaler", func(t *testing.T) {
+		err := cacher.Put(ctx, client, "typed-point", point{X: "1", Y: "2"}, time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		raw, _ := client.GetString(ctx, "typed-point")
+		assert.Equal(t, "1,2", raw, "should be stored as text")
+
+		value, err := cacher.Get[point](ctx, client, "typed-point")
+		assert.NoError(t, err)
+		assert.Equal(t, point{X: "1", Y: "2"}, value)
+	})
+
+	t.Run("UnmarshalerOnly", func(t *testing.T) {
+		err := cacher.Put(ctx, client, "typed-label", label("urgent"), time.Minute*5)
+
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		raw, _ := client.GetString(ctx, "typed-label")
+		assert.Equal(t, `"urgent"`, raw, "should fall back to json without a marshaler")
+
+		value, err := cacher.Get[label](ctx, client, "typed-label")
+		assert.NoError(t, err)
+		assert.Equal(t, label("urgent"), value)
+	})
+
+	t.Run("Structs", func(t *testing.T) {
+		type user struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		}
+
+		var calls int
+
+		for i := 0; i < 2; i++ {
+			value, err := cacher.Remember(ctx, client, "typed-user", time.Minute*5, func(ctx context.Context) (user, error) {
+				calls++
+				return user{ID: 1, Name: "Jane"}, nil
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, user{ID: 1, Name: "Jane"}, value)
+		}
+
+		assert.Equal(t, 1, calls, "should only call the fetcher once")
+
+		raw, _ := client.GetString(ctx, "typed-user")
+		assert.JSONEq(t, `{"id":1,"name":"Jane"}`, raw, "should be stored as json")
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := cacher.Get[time.Duration](ctx, client, "typed-missing")
+		assert.ErrorIs(t, err, cacher.NotFoundError)
+	})
+}
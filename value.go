@@ -0,0 +1,239 @@
+package cacher
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// toBytes converts a value passed to Put into the raw bytes handed to the Store. It follows the same rules as the
+// go-redis client so values are stored identically regardless of the Store in use.
+func toBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int8:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int16:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int32:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(nil, v, 10), nil
+	case uint:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint8:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint16:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint32:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint64:
+		return strconv.AppendUint(nil, v, 10), nil
+	case float32:
+		return strconv.AppendFloat(nil, float64(v), 'f', -1, 64), nil
+	case float64:
+		return strconv.AppendFloat(nil, v, 'f', -1, 64), nil
+	case bool:
+		if v {
+			return []byte("1"), nil
+		}
+
+		return []byte("0"), nil
+	case time.Time:
+		return v.AppendFormat(nil, time.RFC3339Nano), nil
+	case time.Duration:
+		return strconv.AppendInt(nil, v.Nanoseconds(), 10), nil
+	case encoding.BinaryMarshaler:
+		return v.MarshalBinary()
+	case net.IP:
+		return v, nil
+	}
+
+	// dereference pointers to any of the supported types
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		return toBytes(rv.Elem().Interface())
+	}
+
+	return nil, fmt.Errorf("cacher: can't marshal %T (implement encoding.BinaryMarshaler)", value)
+}
+
+// valueCodec converts values of a single type to and from the raw bytes handed to the Store.
+type valueCodec[T any] struct {
+	encode func(val T) ([]byte, error)
+	decode func(data []byte) (T, error)
+}
+
+// codecFor chooses how values of type T are stored. The types supported by toBytes are stored the same way as Put
+// stores them, other types use encoding.BinaryMarshaler or encoding.TextMarshaler when they implement them and JSON
+// otherwise.
+func codecFor[T any]() valueCodec[T] {
+	var zero T
+
+	switch any(zero).(type) {
+	case string:
+		return scalarCodec[T](parseString)
+	case []byte:
+		return scalarCodec[T](parseBytes)
+	case bool:
+		return scalarCodec[T](parseBool)
+	case int:
+		return scalarCodec[T](parseSigned[int](strconv.IntSize))
+	case int8:
+		return scalarCodec[T](parseSigned[int8](8))
+	case int16:
+		return scalarCodec[T](parseSigned[int16](16))
+	case int32:
+		return scalarCodec[T](parseSigned[int32](32))
+	case int64:
+		return scalarCodec[T](parseSigned[int64](64))
+	case uint:
+		return scalarCodec[T](parseUnsigned[uint](strconv.IntSize))
+	case uint8:
+		return scalarCodec[T](parseUnsigned[uint8](8))
+	case uint16:
+		return scalarCodec[T](parseUnsigned[uint16](16))
+	case uint32:
+		return scalarCodec[T](parseUnsigned[uint32](32))
+	case uint64:
+		return scalarCodec[T](parseUnsigned[uint64](64))
+	case float32:
+		return scalarCodec[T](parseFloat32)
+	case float64:
+		return scalarCodec[T](parseFloat64)
+	case time.Time:
+		return scalarCodec[T](parseTime)
+	case time.Duration:
+		return scalarCodec[T](parseSigned[time.Duration](64))
+	case net.IP:
+		return scalarCodec[T](parseIP)
+	}
+
+	// the method set of *T includes the methods of T, a codec is only used when both directions are implemented
+	if implements[encoding.BinaryMarshaler, encoding.BinaryUnmarshaler](&zero) {
+		return valueCodec[T]{
+			encode: func(val T) ([]byte, error) {
+				return any(&val).(encoding.BinaryMarshaler).MarshalBinary()
+			},
+			decode: func(data []byte) (T, error) {
+				var val T
+				err := any(&val).(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+
+				return val, err
+			},
+		}
+	}
+
+	if implements[encoding.TextMarshaler, encoding.TextUnmarshaler](&zero) {
+		return valueCodec[T]{
+			encode: func(val T) ([]byte, error) {
+				return any(&val).(encoding.TextMarshaler).MarshalText()
+			},
+			decode: func(data []byte) (T, error) {
+				var val T
+				err := any(&val).(encoding.TextUnmarshaler).UnmarshalText(data)
+
+				return val, err
+			},
+		}
+	}
+
+	return valueCodec[T]{
+		encode: func(val T) ([]byte, error) {
+			return json.Marshal(val)
+		},
+		decode: func(data []byte) (T, error) {
+			var val T
+			err := json.Unmarshal(data, &val)
+
+			return val, err
+		},
+	}
+}
+
+// implements reports whether v implements both the marshaler M and the unmarshaler U.
+func implements[M any, U any](v any) bool {
+	_, marshals := v.(M)
+	_, unmarshals := v.(U)
+
+	return marshals && unmarshals
+}
+
+// scalarCodec stores values with toBytes and reads them back with parse. V is always the same type as T.
+func scalarCodec[T any, V any](parse func(data []byte) (V, error)) valueCodec[T] {
+	return valueCodec[T]{
+		encode: func(val T) ([]byte, error) {
+			return toBytes(val)
+		},
+		decode: func(data []byte) (T, error) {
+			val, err := parse(data)
+
+			if err != nil {
+				var zero T
+				return zero, err
+			}
+
+			return any(val).(T), nil
+		},
+	}
+}
+
+// The parse functions convert the raw bytes read from the Store back into the values written by toBytes.
+
+func parseString(data []byte) (string, error) {
+	return string(data), nil
+}
+
+func parseBytes(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+func parseBool(data []byte) (bool, error) {
+	return strconv.ParseBool(string(data))
+}
+
+func parseSigned[V ~int | ~int8 | ~int16 | ~int32 | ~int64](bits int) func(data []byte) (V, error) {
+	return func(data []byte) (V, error) {
+		val, err := strconv.ParseInt(string(data), 10, bits)
+		return V(val), err
+	}
+}
+
+func parseUnsigned[V ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64](bits int) func(data []byte) (V, error) {
+	return func(data []byte) (V, error) {
+		val, err := strconv.ParseUint(string(data), 10, bits)
+		return V(val), err
+	}
+}
+
+func parseFloat32(data []byte) (float32, error) {
+	val, err := strconv.ParseFloat(string(data), 32)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return float32(val), nil
+}
+
+func parseFloat64(data []byte) (float64, error) {
+	return strconv.ParseFloat(string(data), 64)
+}
+
+func parseTime(data []byte) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, string(data))
+}
+
+func parseIP(data []byte) (net.IP, error) {
+	return net.IP(data), nil
+}